@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	config "github.com/glup3/TrendyGitHub/internal"
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/github"
+	"github.com/glup3/TrendyGitHub/internal/jobs"
+	lo "github.com/glup3/TrendyGitHub/internal/loader"
+	"github.com/glup3/TrendyGitHub/internal/ratelimit"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+	"github.com/rs/zerolog/log"
+)
+
+// app holds the dependencies shared by all commands
+type app struct {
+	configs *config.Config
+	db      *database.Database
+	// loader, tokens and limiter are nil for commands that don't talk to GitHub
+	loader     lo.Loader
+	tokens     *tokenpool.Pool
+	limiter    *ratelimit.Limiter
+	metrics    *github.Metrics
+	repoJob    *jobs.RepoJob
+	historyJob *jobs.HistoryJob
+}
+
+func newApp(ctx context.Context, cmd command) (*app, error) {
+	configs, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration failed: %w", err)
+	}
+
+	if cmd.needsGitHub {
+		err = configs.CheckGitHub()
+		if err != nil {
+			return nil, fmt.Errorf("loading configuration failed: %w", err)
+		}
+	}
+
+	db, err := database.NewDatabase(ctx, configs.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
+
+	err = db.Ping(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	a := &app{
+		configs: configs,
+		db:      db,
+		metrics: github.NewMetrics(),
+	}
+
+	var githubClient *github.GithubClient
+	if cmd.needsGitHub || (cmd.usesGitHub && configs.HasGitHub()) {
+		githubClient, err = a.newGitHubClient()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		a.loader = lo.NewAPILoader(githubClient, a.limiter)
+	}
+
+	a.repoJob = jobs.NewRepoJob(db, &a.loader)
+	a.historyJob = jobs.NewHistoryJob(db, &a.loader, githubClient)
+
+	return a, nil
+}
+
+// newGitHubClient builds the token pool and the client shared by the loader and the jobs
+func (a *app) newGitHubClient() (*github.GithubClient, error) {
+	// replayed requests never reach GitHub, the pool only drives the per token rate limit checks
+	tokenValues := a.configs.GitHubTokens
+	if a.configs.GitHubReplayDir != "" && len(tokenValues) == 0 {
+		tokenValues = []string{"replay"}
+	}
+
+	tokens, err := tokenpool.New(tokenValues)
+	if err != nil {
+		return nil, err
+	}
+	a.tokens = tokens
+
+	// the loader and the jobs share one client, so one limiter paces and one breaker
+	// pauses all their GraphQL and REST requests
+	a.limiter = ratelimit.New(ratelimit.DefaultOptions)
+	breaker := github.NewBreaker(github.DefaultBreakerOptions)
+
+	// a replay answers with the recorded responses right away: nothing to retry,
+	// authenticate or pace, and the recorded rate limit headers mustn't cause waits
+	replay := a.configs.GitHubReplayDir != ""
+	opts := github.Options{Tokens: tokens}
+
+	var middleware []github.Middleware
+	if !replay {
+		middleware = github.DefaultMiddleware(tokens, a.limiter, breaker)
+		opts.QueryRetry = github.DefaultRetryPolicy
+	}
+	if a.configs.LogGitHubRequests {
+		middleware = append(middleware, github.Log())
+	}
+	middleware = append(middleware, github.Measure(a.metrics))
+
+	switch {
+	case a.configs.GitHubRecordDir != "":
+		middleware = append(middleware, github.Record(a.configs.GitHubRecordDir))
+	case replay:
+		middleware = append(middleware, github.Replay(a.configs.GitHubReplayDir))
+	}
+
+	return github.NewClient(opts, middleware...), nil
+}
+
+func (a *app) close() {
+	a.db.Close()
+
+	totals := a.metrics.Snapshot()
+	for _, api := range []tokenpool.API{tokenpool.GraphQL, tokenpool.REST} {
+		if totals[api].Requests == 0 {
+			continue
+		}
+
+		log.Info().
+			Str("api", string(api)).
+			Int("requests", totals[api].Requests).
+			Int("failures", totals[api].Failures).
+			Dur("duration", totals[api].Duration).
+			Msg("GitHub requests")
+	}
+}
+
+// setLockWait makes the jobs wait for or fail on a job running in another process,
+// the scheduler keeps skipping them instead
+func (a *app) setLockWait(wait bool) {
+	mode := jobs.LockFail
+	if wait {
+		mode = jobs.LockWait
+	}
+
+	a.repoJob.SetLockMode(mode)
+	a.historyJob.SetLockMode(mode)
+}
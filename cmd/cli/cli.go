@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/glup3/TrendyGitHub/internal/jobs"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	// exitInterrupted follows the shell convention of 128 + SIGINT
+	exitInterrupted = 130
+)
+
+type runFunc func(ctx context.Context, app *app) error
+
+type command struct {
+	name    string
+	summary string
+	// args describes the positional arguments, commands without it reject them
+	args string
+	// anySchema lets the command run against a database that isn't at the embedded schema version
+	anySchema bool
+	// needsGitHub commands refuse to run without GitHub tokens, usesGitHub ones talk to GitHub
+	// only when tokens are configured, all others don't get a GitHub client at all
+	needsGitHub bool
+	usesGitHub  bool
+	// setup registers the flags of the command and returns what to run once they are parsed
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+// usageError is returned by commands for invalid flag values
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+func newUsageError(format string, a ...any) error {
+	return usageError{message: fmt.Sprintf(format, a...)}
+}
+
+func run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		if len(args) > 1 {
+			if cmd, ok := findCommand(args[1]); ok {
+				fs, _ := newFlagSet(cmd)
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return exitOK
+			}
+		}
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	fs, runCmd := newFlagSet(cmd)
+	err := fs.Parse(args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() > 0 && cmd.args == "" {
+		fmt.Fprintf(os.Stderr, "unexpected arguments: %s\n\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+
+	a, err := newApp(ctx, cmd)
+	if err != nil {
+		log.Error().Err(err).Msg("starting failed")
+		return exitError
+	}
+	defer a.close()
+
+	if !cmd.anySchema {
+		err = a.db.CheckSchemaVersion(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("refusing to run against this database")
+			return exitError
+		}
+	}
+
+	err = runCmd(ctx, a)
+	if err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "%s\n\n", usageErr.message)
+			fs.Usage()
+			return exitUsage
+		}
+
+		if errors.Is(err, jobs.ErrSkipped) {
+			log.Warn().Str("command", name).Msg("command skipped because another process is running it, --wait waits for it")
+			return exitError
+		}
+
+		if errors.Is(err, context.Canceled) {
+			log.Warn().Str("command", name).Msg("command interrupted")
+			return exitInterrupted
+		}
+
+		log.Error().Err(err).Str("command", name).Msg("command failed")
+		return exitError
+	}
+
+	return exitOK
+}
+
+func newFlagSet(cmd command) (*flag.FlagSet, runFunc) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	runCmd := cmd.setup(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: tgh %s [flags]", cmd.name)
+		if cmd.args != "" {
+			fmt.Fprintf(fs.Output(), " %s", cmd.args)
+		}
+		fmt.Fprintf(fs.Output(), "\n\n%s\n", cmd.summary)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(fs.Output(), "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs, runCmd
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintf(out, "Usage: tgh <command> [flags]\n\nCommands:\n")
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nRun 'tgh help <command>' for the flags of a command.\n")
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/jobs"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/glup3/TrendyGitHub/internal/scheduler"
+	"github.com/glup3/TrendyGitHub/internal/server"
+)
+
+const maxGraphqlStarCount = 1_000_000
+
+// waitFlag registers --wait on commands that skip while another process runs them
+func waitFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("wait", false, "wait for the job running in another process instead of skipping")
+}
+
+var commands = []command{
+	{
+		name:        "search",
+		needsGitHub: true,
+		summary:     "crawl repositories with the GitHub search and snapshot their star counts",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+
+			return func(ctx context.Context, a *app) error {
+				a.setLockWait(*wait)
+				err := a.repoJob.Search(ctx)
+				if err != nil {
+					return err
+				}
+
+				return a.historyJob.CreateSnapshot(ctx)
+			}
+		},
+	},
+	{
+		name:        "history",
+		needsGitHub: true,
+		summary:     "fetch missing star histories with the GraphQL API",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+			limit := fs.Int("limit", 0, "repositories to fetch, 0 fetches until the rate limit is exceeded")
+
+			return func(ctx context.Context, a *app) error {
+				if *limit < 0 {
+					return newUsageError("--limit must not be negative")
+				}
+
+				a.setLockWait(*wait)
+				return a.historyJob.FetchHistory(ctx, *limit)
+			}
+		},
+	},
+	{
+		name:        "history-40k",
+		needsGitHub: true,
+		summary:     "fetch missing star histories of smaller repositories with the REST API",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+			limit := fs.Int("limit", 0, "repositories to fetch, 0 fetches until the rate limit is exceeded")
+			maxStars := fs.Int("max-stars", jobs.MaxRestStarCount, "only fetch repositories with at most this many stars")
+
+			return func(ctx context.Context, a *app) error {
+				if *limit < 0 {
+					return newUsageError("--limit must not be negative")
+				}
+				if *maxStars < 1 || *maxStars > jobs.MaxRestStarCount {
+					return newUsageError("--max-stars must be between 1 and %d", jobs.MaxRestStarCount)
+				}
+
+				a.setLockWait(*wait)
+				return a.historyJob.FetchHistoryUnder40kStars(ctx, *maxStars, *limit)
+			}
+		},
+	},
+	{
+		name:        "repair",
+		needsGitHub: true,
+		summary:     "repair broken star histories with the GraphQL API",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+			maxStars := fs.Int("max-stars", maxGraphqlStarCount, "only repair repositories with at most this many stars")
+
+			return func(ctx context.Context, a *app) error {
+				if *maxStars < 1 {
+					return newUsageError("--max-stars must be positive")
+				}
+
+				a.setLockWait(*wait)
+				return a.historyJob.Repair(ctx, *maxStars)
+			}
+		},
+	},
+	{
+		name:        "repair-40k",
+		needsGitHub: true,
+		summary:     "repair broken star histories of smaller repositories with the REST API",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+			maxStars := fs.Int("max-stars", jobs.MaxRestStarCount, "only repair repositories with at most this many stars")
+
+			return func(ctx context.Context, a *app) error {
+				if *maxStars < 1 || *maxStars > jobs.MaxRestStarCount {
+					return newUsageError("--max-stars must be between 1 and %d", jobs.MaxRestStarCount)
+				}
+
+				a.setLockWait(*wait)
+				return a.historyJob.Repair40k(ctx, *maxStars)
+			}
+		},
+	},
+	{
+		name:    "reset",
+		summary: "reset the star count cursor of the search",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+			settingsID := fs.Int("settings-id", 1, "id of the settings row to reset")
+
+			return func(ctx context.Context, a *app) error {
+				a.setLockWait(*wait)
+				return a.repoJob.ResetStarCountCursor(ctx, *settingsID)
+			}
+		},
+	},
+	{
+		name:    "refresh",
+		summary: "refresh the trend views and persist their rankings",
+		setup: func(fs *flag.FlagSet) runFunc {
+			wait := waitFlag(fs)
+
+			return func(ctx context.Context, a *app) error {
+				a.setLockWait(*wait)
+				return a.historyJob.RefreshViews(ctx)
+			}
+		},
+	},
+	{
+		name:        "track",
+		needsGitHub: true,
+		summary:     "track a repository regardless of its star count",
+		args:        "<owner/name>...",
+		setup: func(fs *flag.FlagSet) runFunc {
+			watchlist := fs.String("watchlist", repository.DefaultWatchlist, "watchlist to add the repositories to")
+
+			return func(ctx context.Context, a *app) error {
+				if fs.NArg() == 0 {
+					return newUsageError("expected at least one repository like glup3/TrendyGitHub")
+				}
+
+				return trackRepos(ctx, a, *watchlist, fs.Args())
+			}
+		},
+	},
+	{
+		name:    "untrack",
+		summary: "remove a repository from a watchlist",
+		args:    "<owner/name>...",
+		setup: func(fs *flag.FlagSet) runFunc {
+			watchlist := fs.String("watchlist", repository.DefaultWatchlist, "watchlist to remove the repositories from")
+
+			return func(ctx context.Context, a *app) error {
+				if fs.NArg() == 0 {
+					return newUsageError("expected at least one repository like glup3/TrendyGitHub")
+				}
+
+				return untrackRepos(ctx, a, *watchlist, fs.Args())
+			}
+		},
+	},
+	{
+		name:       "tracked",
+		usesGitHub: true,
+		summary:    "list the tracked repositories",
+		setup: func(fs *flag.FlagSet) runFunc {
+			watchlist := fs.String("watchlist", "", "only list this watchlist")
+			refresh := fs.Bool("refresh", false, "update the star counts of all tracked repositories first")
+
+			return func(ctx context.Context, a *app) error {
+				if *refresh {
+					if a.loader == nil {
+						return fmt.Errorf("--refresh needs GITHUB_TOKEN or GITHUB_TOKENS")
+					}
+
+					err := a.repoJob.RefreshTracked(ctx)
+					if err != nil {
+						return err
+					}
+				}
+
+				return printTracked(ctx, a, *watchlist)
+			}
+		},
+	},
+	{
+		name:        "run",
+		needsGitHub: true,
+		summary:     "run the jobs on a schedule until interrupted",
+		setup: func(fs *flag.FlagSet) runFunc {
+			schedulePath := fs.String("schedule", "", "schedule file, defaults to the built-in schedule")
+
+			return func(ctx context.Context, a *app) error {
+				schedule, err := loadSchedule(*schedulePath)
+				if err != nil {
+					return newUsageError("%v", err)
+				}
+
+				s, err := scheduler.New(schedule, schedulerSteps(a))
+				if err != nil {
+					return newUsageError("%v", err)
+				}
+
+				s.Run(ctx)
+				return nil
+			}
+		},
+	},
+	{
+		name:       "status",
+		usesGitHub: true,
+		summary:    "print the crawl state, backlogs, view refreshes and GitHub API budgets",
+		setup: func(fs *flag.FlagSet) runFunc {
+			asJSON := fs.Bool("json", false, "print the status as JSON")
+
+			return func(ctx context.Context, a *app) error {
+				return printStatus(ctx, a, *asJSON)
+			}
+		},
+	},
+	{
+		name:    "runs",
+		summary: "list the latest job runs with the API units they spent",
+		setup: func(fs *flag.FlagSet) runFunc {
+			mode := fs.String("mode", "", "only list runs of this command, e.g. search or history-40k")
+			limit := fs.Int("limit", 20, "runs to print")
+
+			return func(ctx context.Context, a *app) error {
+				return printRuns(ctx, a, *mode, *limit)
+			}
+		},
+	},
+	{
+		name:    "trending",
+		summary: "print the top trending repositories of a period",
+		setup: func(fs *flag.FlagSet) runFunc {
+			var opts trendingOptions
+			fs.StringVar(&opts.period, "period", string(repository.PeriodDaily), "daily, weekly or monthly")
+			fs.StringVar(&opts.language, "language", "", "only print repositories with this primary language")
+			fs.StringVar(&opts.uses, "uses", "", "only print repositories using this language")
+			fs.IntVar(&opts.limit, "limit", 25, "repositories to print")
+			fs.StringVar(&opts.format, "format", "table", "table, json or csv")
+
+			return func(ctx context.Context, a *app) error {
+				return printTrending(ctx, a, opts)
+			}
+		},
+	},
+	{
+		name:    "repo",
+		summary: "inspect the crawl state, trend ranks and star history of a repository",
+		args:    "<owner/name>",
+		setup: func(fs *flag.FlagSet) runFunc {
+			today := time.Now().UTC()
+			from := fs.String("from", today.AddDate(0, 0, -defaultHistoryDays).Format(dateLayout), "first date of the history (YYYY-MM-DD)")
+			to := fs.String("to", today.Format(dateLayout), "last date of the history (YYYY-MM-DD)")
+			bucket := fs.String("bucket", string(repository.BucketDay), "day, week or month")
+
+			return func(ctx context.Context, a *app) error {
+				if fs.NArg() != 1 {
+					return newUsageError("expected exactly one repository like glup3/TrendyGitHub")
+				}
+
+				return printRepo(ctx, a, fs.Arg(0), *from, *to, *bucket)
+			}
+		},
+	},
+	{
+		name:    "asof",
+		summary: "print the trends as they were computed on a past date",
+		setup: func(fs *flag.FlagSet) runFunc {
+			var opts asOfOptions
+			fs.StringVar(&opts.date, "date", time.Now().UTC().Format(dateLayout), "date of the trends (YYYY-MM-DD)")
+			fs.StringVar(&opts.window, "window", string(repository.PeriodDaily), "daily, weekly, monthly or a number of days")
+			fs.StringVar(&opts.language, "language", "", "only print repositories with this primary language")
+			fs.StringVar(&opts.uses, "uses", "", "only print repositories using this language")
+			fs.IntVar(&opts.limit, "limit", 25, "repositories to print")
+			fs.StringVar(&opts.format, "format", "table", "table, json or csv")
+
+			return func(ctx context.Context, a *app) error {
+				return printTrendsAsOf(ctx, a, opts)
+			}
+		},
+	},
+	{
+		name:      "migrate",
+		summary:   "apply or roll back the embedded database migrations",
+		args:      "up|down|version",
+		anySchema: true,
+		setup: func(fs *flag.FlagSet) runFunc {
+			steps := fs.Int("steps", 1, "migrations to roll back with down")
+
+			return func(ctx context.Context, a *app) error {
+				if fs.NArg() != 1 {
+					return newUsageError("expected one of up, down or version")
+				}
+
+				return runMigrate(ctx, a, fs.Arg(0), *steps)
+			}
+		},
+	},
+	{
+		name:    "serve",
+		summary: "serve the trends API and homepage",
+		setup: func(fs *flag.FlagSet) runFunc {
+			addr := fs.String("addr", "", "address to listen on, defaults to SERVER_ADDR")
+
+			return func(ctx context.Context, a *app) error {
+				if *addr == "" {
+					*addr = a.configs.ServerAddr
+				}
+
+				srv := server.NewServer(a.db, server.Options{
+					RateLimitBurst:     a.configs.RateLimitBurst,
+					RateLimitPerMinute: a.configs.RateLimitPerMinute,
+					APIKeys:            a.configs.APIKeys,
+				})
+
+				return srv.ListenAndServe(ctx, *addr)
+			}
+		},
+	},
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func runMigrate(ctx context.Context, a *app, action string, steps int) error {
+	switch action {
+	case "up", "down":
+	case "version":
+		return printSchemaVersion(ctx, a)
+	default:
+		return newUsageError("unknown migrate action %s, expected up, down or version", action)
+	}
+
+	if action == "down" && steps < 1 {
+		return newUsageError("--steps must be positive")
+	}
+
+	m, err := database.NewMigrate(a.configs.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	// migrate can't be cancelled mid-migration, it stops after the current one
+	go func() {
+		<-ctx.Done()
+		m.GracefulStop <- true
+	}()
+
+	if action == "up" {
+		err = m.Up()
+	} else {
+		err = m.Steps(-steps)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Info().Msg("schema is up to date")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migrating %s: %w", action, err)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return printSchemaVersion(ctx, a)
+}
+
+func printSchemaVersion(ctx context.Context, a *app) error {
+	latest, err := database.LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := a.db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("database version %d (dirty: %t), embedded migrations up to %d\n", version, dirty, latest)
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/chart"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultHistoryDays = 90
+	sparklineWidth     = 60
+	// historyTableRows keeps the table of long ranges readable, the sparkline covers all of them
+	historyTableRows = 30
+)
+
+type repoInspection struct {
+	Details repository.RepoDetails
+	From    time.Time
+	To      time.Time
+	Bucket  repository.HistoryBucket
+	Ranks   []periodRank
+	History []repository.StarHistoryPoint
+}
+
+type periodRank struct {
+	Period    repository.TrendPeriod
+	Rank      int
+	StarsDiff int
+}
+
+func printRepo(ctx context.Context, a *app, nameWithOwner string, fromValue string, toValue string, bucketValue string) error {
+	from, err := time.Parse(dateLayout, fromValue)
+	if err != nil {
+		return newUsageError("--from must be formatted as YYYY-MM-DD: %s", fromValue)
+	}
+
+	to, err := time.Parse(dateLayout, toValue)
+	if err != nil {
+		return newUsageError("--to must be formatted as YYYY-MM-DD: %s", toValue)
+	}
+
+	if from.After(to) {
+		return newUsageError("--from must not be after --to")
+	}
+
+	bucket, err := repository.ParseHistoryBucket(bucketValue)
+	if err != nil {
+		return newUsageError("--bucket must be day, week or month: %s", bucketValue)
+	}
+
+	inspection := repoInspection{From: from, To: to, Bucket: bucket}
+
+	inspection.Details, err = repository.NewRepoRepository(a.db).GetDetails(ctx, nameWithOwner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("repository %s is not crawled", nameWithOwner)
+	}
+	if err != nil {
+		return err
+	}
+
+	trendRepository := repository.NewTrendRepository(a.db)
+	for _, period := range repository.TrendPeriods {
+		rank, err := trendRepository.GetRank(ctx, period, inspection.Details.Id)
+		if err != nil {
+			return err
+		}
+
+		starsDiff, err := trendRepository.GetStarsDiff(ctx, period, inspection.Details.Id)
+		if err != nil {
+			return err
+		}
+
+		inspection.Ranks = append(inspection.Ranks, periodRank{Period: period, Rank: rank, StarsDiff: starsDiff})
+	}
+
+	inspection.History, err = repository.NewHistoryRepository(a.db).GetStarHistory(ctx, inspection.Details.Id, from, to, bucket)
+	if err != nil {
+		return err
+	}
+
+	return writeRepo(os.Stdout, inspection)
+}
+
+func writeRepo(out io.Writer, inspection repoInspection) error {
+	details := inspection.Details
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "REPOSITORY\n")
+	fmt.Fprintf(w, "  name\t%s\n", details.NameWithOwner)
+	fmt.Fprintf(w, "  id\t%d (%s)\n", details.Id, details.GithubId)
+	fmt.Fprintf(w, "  description\t%s\n", defaultString(details.Description, "-"))
+	fmt.Fprintf(w, "  language\t%s\n", defaultString(details.PrimaryLanguage, "-"))
+	fmt.Fprintf(w, "  languages\t%s\n", defaultString(strings.Join(details.Languages, ", "), "-"))
+	fmt.Fprintf(w, "  stars\t%d\n", details.StarCount)
+	fmt.Fprintf(w, "  forks\t%d\n", details.ForkCount)
+	fmt.Fprintf(w, "  history missing\t%t\n", details.HistoryMissing)
+	if details.RepairUntil != nil {
+		fmt.Fprintf(w, "  repair pending\tuntil %s\n", details.RepairUntil.Format(dateLayout))
+	} else {
+		fmt.Fprintf(w, "  repair pending\t-\n")
+	}
+
+	fmt.Fprintf(w, "\nPERIOD\tRANK\tDIFF\n")
+	for _, rank := range inspection.Ranks {
+		if rank.Rank == 0 {
+			fmt.Fprintf(w, "  %s\tnot trending\t\n", rank.Period)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%d\t+%d\n", rank.Period, rank.Rank, rank.StarsDiff)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nHISTORY %s - %s by %s\n", inspection.From.Format(dateLayout), inspection.To.Format(dateLayout), inspection.Bucket)
+	if len(inspection.History) == 0 {
+		fmt.Fprintf(out, "  no stars_history_hyper rows in range\n")
+		return nil
+	}
+
+	values := make([]int, len(inspection.History))
+	for i, point := range inspection.History {
+		values[i] = point.StarCount
+	}
+	fmt.Fprintf(out, "  %s  %s → %s\n\n", chart.Sparkline(values, sparklineWidth), chart.FormatCompact(values[0]), chart.FormatCompact(values[len(values)-1]))
+
+	start := max(0, len(inspection.History)-historyTableRows)
+	if start > 0 {
+		fmt.Fprintf(out, "  last %d of %d %ss\n", historyTableRows, len(inspection.History), inspection.Bucket)
+	}
+
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "DATE\tSTARS\tDIFF\t\n")
+	for i := start; i < len(inspection.History); i++ {
+		point := inspection.History[i]
+		diff := "-"
+		if i > 0 {
+			diff = fmt.Sprintf("%+d", point.StarCount-inspection.History[i-1].StarCount)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t\n", point.Date.Format(dateLayout), point.StarCount, diff)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+)
+
+func printRuns(ctx context.Context, a *app, mode string, limit int) error {
+	if limit < 1 {
+		return newUsageError("--limit must be positive")
+	}
+
+	runs, err := repository.NewRunRepository(a.db).List(ctx, mode, limit)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\tMODE\tSTATUS\tSTARTED\tDURATION\tGRAPHQL\tREST\tREPOS\tCURSOR\tERROR\n")
+	for _, run := range runs {
+		duration := "-"
+		if run.FinishedAt != nil {
+			duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Second).String()
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			run.Id,
+			run.Mode,
+			run.Status,
+			run.StartedAt.Local().Format(time.DateTime),
+			duration,
+			run.GraphqlUnits,
+			run.RestUnits,
+			run.ReposProcessed,
+			defaultString(run.Cursor, "-"),
+			run.Error,
+		)
+	}
+
+	return w.Flush()
+}
+
+func defaultString(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
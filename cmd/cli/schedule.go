@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/glup3/TrendyGitHub/internal/jobs"
+	"github.com/glup3/TrendyGitHub/internal/scheduler"
+)
+
+// defaultSchedule replaces the crontab the docker image used to install
+const defaultSchedule = `
+# name        minute hour day month weekday  steps (in order)
+search        0-20   *    *   *     *        search snapshot
+tracked       30     *    *   *     *        refresh-tracked snapshot
+history-40k   0      *    *   *     *        history-40k
+repair-40k    */5    *    *   *     *        repair-40k
+reset         59     *    *   *     *        reset
+refresh       0      *    *   *     *        refresh after=search
+`
+
+// schedulerSteps maps the step names of a schedule to the jobs with their default flags
+func schedulerSteps(a *app) map[string]scheduler.StepFunc {
+	return map[string]scheduler.StepFunc{
+		"search": func(ctx context.Context) error {
+			return a.repoJob.Search(ctx)
+		},
+		"refresh-tracked": a.repoJob.RefreshTracked,
+		"snapshot":        a.historyJob.CreateSnapshot,
+		"history": func(ctx context.Context) error {
+			return a.historyJob.FetchHistory(ctx, 0)
+		},
+		"history-40k": func(ctx context.Context) error {
+			return a.historyJob.FetchHistoryUnder40kStars(ctx, jobs.MaxRestStarCount, 0)
+		},
+		"repair": func(ctx context.Context) error {
+			return a.historyJob.Repair(ctx, maxGraphqlStarCount)
+		},
+		"repair-40k": func(ctx context.Context) error {
+			return a.historyJob.Repair40k(ctx, jobs.MaxRestStarCount)
+		},
+		"reset": func(ctx context.Context) error {
+			return a.repoJob.ResetStarCountCursor(ctx, 1)
+		},
+		"refresh": a.historyJob.RefreshViews,
+	}
+}
+
+func loadSchedule(path string) ([]scheduler.Job, error) {
+	text := defaultSchedule
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading schedule: %w", err)
+		}
+		text = string(content)
+	}
+
+	return scheduler.ParseJobs(text)
+}
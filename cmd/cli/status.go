@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+)
+
+type crawlStatus struct {
+	LastSnapshot *time.Time         `json:"last_snapshot"`
+	Settings     settingsStatus     `json:"settings"`
+	GraphQL      rateLimitStatus    `json:"graphql"`
+	REST         rateLimitStatus    `json:"rest"`
+	Missing      []starTierStatus   `json:"history_missing"`
+	Repairs      []starTierStatus   `json:"history_repairs"`
+	Views        []viewRefreshState `json:"views"`
+	Tokens       []tokenStatus      `json:"tokens"`
+}
+
+type settingsStatus struct {
+	CurrentMaxStarCount int  `json:"current_max_star_count"`
+	MinStarCount        int  `json:"min_star_count"`
+	Enabled             bool `json:"enabled"`
+}
+
+type rateLimitStatus struct {
+	ResetAt   *time.Time `json:"reset_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Limit     int        `json:"limit"`
+	Remaining int        `json:"remaining"`
+}
+
+type tokenStatus struct {
+	Name    string          `json:"name"`
+	GraphQL rateLimitStatus `json:"graphql"`
+	REST    rateLimitStatus `json:"rest"`
+}
+
+type starTierStatus struct {
+	Tier     string `json:"tier"`
+	MaxStars int    `json:"max_stars,omitempty"`
+	Count    int    `json:"count"`
+}
+
+type viewRefreshState struct {
+	RefreshedAt time.Time `json:"refreshed_at"`
+	View        string    `json:"view"`
+	DurationMs  int64     `json:"duration_ms"`
+}
+
+func printStatus(ctx context.Context, a *app, asJSON bool) error {
+	status, err := loadStatus(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+
+	return writeStatus(os.Stdout, status)
+}
+
+func writeStatus(out io.Writer, status crawlStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "SEARCH\n")
+	fmt.Fprintf(w, "  enabled\t%t\n", status.Settings.Enabled)
+	fmt.Fprintf(w, "  cursor\t%d\n", status.Settings.CurrentMaxStarCount)
+	fmt.Fprintf(w, "  min stars\t%d\n", status.Settings.MinStarCount)
+	if status.LastSnapshot != nil {
+		fmt.Fprintf(w, "  last snapshot\t%s\n", status.LastSnapshot.Format(dateLayout))
+	} else {
+		fmt.Fprintf(w, "  last snapshot\t-\n")
+	}
+
+	fmt.Fprintf(w, "\nSTARS\tHISTORY MISSING\tREPAIRS\n")
+	for i, tier := range status.Missing {
+		fmt.Fprintf(w, "  %s\t%d\t%d\n", tier.Tier, tier.Count, status.Repairs[i].Count)
+	}
+
+	fmt.Fprintf(w, "\nVIEW\tREFRESHED\tTOOK\n")
+	for _, view := range status.Views {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", view.View, view.RefreshedAt.Local().Format(time.DateTime), time.Duration(view.DurationMs)*time.Millisecond)
+	}
+
+	fmt.Fprintf(w, "\nAPI\tREMAINING\tRESETS\n")
+	for _, api := range []struct {
+		name  string
+		limit rateLimitStatus
+	}{{"graphql", status.GraphQL}, {"rest", status.REST}} {
+		if api.limit.Error != "" {
+			fmt.Fprintf(w, "  %s\terror: %s\t\n", api.name, api.limit.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%d/%d\t%s\n", api.name, api.limit.Remaining, api.limit.Limit, api.limit.ResetAt.Local().Format(time.TimeOnly))
+	}
+
+	fmt.Fprintf(w, "\nTOKEN\tGRAPHQL\tREST\n")
+	for _, token := range status.Tokens {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", token.Name, formatBudget(token.GraphQL), formatBudget(token.REST))
+	}
+
+	return w.Flush()
+}
+
+func formatBudget(limit rateLimitStatus) string {
+	if limit.ResetAt == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d until %s", limit.Remaining, limit.Limit, limit.ResetAt.Local().Format(time.TimeOnly))
+}
+
+func loadStatus(ctx context.Context, a *app) (crawlStatus, error) {
+	var status crawlStatus
+
+	settings, err := repository.NewSettingsRepository(a.db).Load(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.Settings = settingsStatus{
+		CurrentMaxStarCount: settings.CurrentMaxStarCount,
+		MinStarCount:        settings.MinStarCount,
+		Enabled:             settings.IsEnabled,
+	}
+
+	historyRepository := repository.NewHistoryRepository(a.db)
+
+	status.LastSnapshot, err = historyRepository.GetLastSnapshotDate(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	missing, err := repository.NewRepoRepository(a.db).CountMissingByTier(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.Missing = mapStarTiers(missing)
+
+	repairs, err := historyRepository.CountBrokenByTier(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.Repairs = mapStarTiers(repairs)
+
+	refreshes, err := historyRepository.GetViewRefreshes(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.Views = []viewRefreshState{}
+	for _, refresh := range refreshes {
+		status.Views = append(status.Views, viewRefreshState{
+			RefreshedAt: refresh.RefreshedAt,
+			View:        refresh.View,
+			DurationMs:  refresh.Duration.Milliseconds(),
+		})
+	}
+
+	status.Tokens = []tokenStatus{}
+	if a.loader == nil {
+		status.GraphQL.Error = "no GitHub tokens configured"
+		status.REST.Error = status.GraphQL.Error
+		return status, nil
+	}
+
+	// GitHub being unreachable is part of the status, not a failure of the command
+	graphql, err := a.loader.GetRateLimit(ctx)
+	if err != nil {
+		status.GraphQL.Error = err.Error()
+	} else {
+		status.GraphQL = rateLimitStatus{ResetAt: &graphql.ResetAt, Limit: graphql.Limit, Remaining: graphql.Remaining}
+	}
+
+	rest, err := a.loader.GetRateLimitRest(ctx)
+	if err != nil {
+		status.REST.Error = err.Error()
+	} else {
+		resetAt := time.Unix(int64(rest.Rate.Reset), 0)
+		status.REST = rateLimitStatus{ResetAt: &resetAt, Limit: rest.Rate.Limit, Remaining: rest.Rate.Remaining}
+	}
+
+	// the pool learned the budget of every token from the calls above
+	for _, budget := range a.tokens.Budgets() {
+		status.Tokens = append(status.Tokens, tokenStatus{
+			Name:    budget.Name,
+			GraphQL: mapBudget(budget.GraphQL),
+			REST:    mapBudget(budget.REST),
+		})
+	}
+
+	return status, nil
+}
+
+func mapBudget(budget tokenpool.Budget) rateLimitStatus {
+	if !budget.Known {
+		return rateLimitStatus{}
+	}
+	return rateLimitStatus{ResetAt: &budget.ResetAt, Limit: budget.Limit, Remaining: budget.Remaining}
+}
+
+func mapStarTiers(tiers []repository.StarTier) []starTierStatus {
+	mapped := make([]starTierStatus, len(tiers))
+	for i, tier := range tiers {
+		mapped[i] = starTierStatus{Tier: tier.Label, MaxStars: tier.MaxStars, Count: tier.Count}
+	}
+	return mapped
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+)
+
+func trackRepos(ctx context.Context, a *app, watchlist string, names []string) error {
+	for _, name := range names {
+		repo, err := a.repoJob.Track(ctx, watchlist, name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("tracking %s (%d stars) on %s\n", repo.NameWithOwner, repo.StarCount, watchlist)
+	}
+
+	return nil
+}
+
+func untrackRepos(ctx context.Context, a *app, watchlist string, names []string) error {
+	watchlistRepository := repository.NewWatchlistRepository(a.db)
+
+	for _, name := range names {
+		removed, err := watchlistRepository.Untrack(ctx, watchlist, name)
+		if err != nil {
+			return err
+		}
+
+		if !removed {
+			return fmt.Errorf("%s is not on watchlist %s", name, watchlist)
+		}
+
+		fmt.Printf("stopped tracking %s on %s\n", name, watchlist)
+	}
+
+	return nil
+}
+
+func printTracked(ctx context.Context, a *app, watchlist string) error {
+	tracked, err := repository.NewWatchlistRepository(a.db).List(ctx, watchlist)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "WATCHLIST\tREPOSITORY\tSTARS\tHISTORY MISSING\tADDED\n")
+	for _, repo := range tracked {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%t\t%s\n",
+			repo.Watchlist,
+			repo.NameWithOwner,
+			repo.StarCount,
+			repo.HistoryMissing,
+			repo.AddedAt.Local().Format(time.DateTime),
+		)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const dateLayout = "2006-01-02"
+
+var trendFormats = []string{"table", "json", "csv"}
+
+type trendOutput struct {
+	NameWithOwner   string  `json:"name_with_owner"`
+	Description     string  `json:"description"`
+	PrimaryLanguage string  `json:"primary_language"`
+	Rank            int     `json:"rank"`
+	StarCount       int     `json:"star_count"`
+	StarsDiff       int     `json:"stars_diff"`
+	Growth          float64 `json:"growth_percent"`
+}
+
+type trendingOptions struct {
+	period   string
+	language string
+	uses     string
+	format   string
+	limit    int
+}
+
+func printTrending(ctx context.Context, a *app, opts trendingOptions) error {
+	period, err := repository.ParseTrendPeriod(opts.period)
+	if err != nil {
+		return newUsageError("--period must be daily, weekly or monthly: %s", opts.period)
+	}
+
+	err = validateTrendOutput(opts.format, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	repoRepository := repository.NewRepoRepository(a.db)
+
+	language, err := resolveLanguage(ctx, repoRepository, opts.language)
+	if err != nil {
+		return err
+	}
+
+	uses, err := resolveLanguage(ctx, repoRepository, opts.uses)
+	if err != nil {
+		return err
+	}
+
+	trends, err := repository.NewTrendRepository(a.db).GetTrends(ctx, repository.TrendQuery{
+		Period:   period,
+		Language: language,
+		Uses:     uses,
+		Limit:    opts.limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeTrends(os.Stdout, opts.format, trends)
+}
+
+type asOfOptions struct {
+	date     string
+	window   string
+	language string
+	uses     string
+	format   string
+	limit    int
+}
+
+func printTrendsAsOf(ctx context.Context, a *app, opts asOfOptions) error {
+	date, err := time.Parse(dateLayout, opts.date)
+	if err != nil {
+		return newUsageError("--date must be formatted as YYYY-MM-DD: %s", opts.date)
+	}
+
+	window, err := parseWindow(opts.window)
+	if err != nil {
+		return err
+	}
+
+	err = validateTrendOutput(opts.format, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	repoRepository := repository.NewRepoRepository(a.db)
+
+	language, err := resolveLanguage(ctx, repoRepository, opts.language)
+	if err != nil {
+		return err
+	}
+
+	uses, err := resolveLanguage(ctx, repoRepository, opts.uses)
+	if err != nil {
+		return err
+	}
+
+	trends, err := repository.NewTrendRepository(a.db).GetTrendsAsOf(ctx, repository.TrendAsOfQuery{
+		Date:     date,
+		Window:   window,
+		Language: language,
+		Uses:     uses,
+		Limit:    opts.limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeTrends(os.Stdout, opts.format, trends)
+}
+
+func validateTrendOutput(format string, limit int) error {
+	if limit < 1 {
+		return newUsageError("--limit must be positive")
+	}
+
+	for _, f := range trendFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return newUsageError("--format must be table, json or csv: %s", format)
+}
+
+// resolveLanguage returns the spelling of a language stored in the languages table
+func resolveLanguage(ctx context.Context, repoRepository *repository.RepoRepository, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	language, err := repoRepository.FindLanguage(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", newUsageError("unknown language %s", name)
+	}
+
+	return language, err
+}
+
+func writeTrends(out io.Writer, format string, trends []repository.Trend) error {
+	outputs := make([]trendOutput, len(trends))
+	for i, trend := range trends {
+		outputs[i] = trendOutput{
+			NameWithOwner:   trend.NameWithOwner,
+			Description:     trend.Description,
+			PrimaryLanguage: trend.PrimaryLanguage,
+			Rank:            i + 1,
+			StarCount:       trend.StarCount,
+			StarsDiff:       trend.StarsDiff,
+			Growth:          trend.Growth(),
+		}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(outputs)
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write([]string{"rank", "name_with_owner", "primary_language", "star_count", "stars_diff", "growth_percent", "description"})
+		for _, trend := range outputs {
+			w.Write([]string{
+				strconv.Itoa(trend.Rank),
+				trend.NameWithOwner,
+				trend.PrimaryLanguage,
+				strconv.Itoa(trend.StarCount),
+				strconv.Itoa(trend.StarsDiff),
+				strconv.FormatFloat(trend.Growth, 'f', 2, 64),
+				trend.Description,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(w, "RANK\tREPOSITORY\tLANGUAGE\tSTARS\tDIFF\tGROWTH\t\n")
+		for _, trend := range outputs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t+%d\t%.1f%%\t\n", trend.Rank, trend.NameWithOwner, trend.PrimaryLanguage, trend.StarCount, trend.StarsDiff, trend.Growth)
+		}
+		return w.Flush()
+	}
+}
+
+func parseWindow(value string) (string, error) {
+	if period, err := repository.ParseTrendPeriod(value); err == nil {
+		return period.Window(), nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 {
+		return "", newUsageError("--window must be daily, weekly, monthly or a number of days: %s", value)
+	}
+	if days > repository.MaxAsOfDays {
+		return "", newUsageError("--window must be at most %d days: %s", repository.MaxAsOfDays, value)
+	}
+
+	return fmt.Sprintf("%d day", days), nil
+}
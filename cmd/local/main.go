@@ -8,6 +8,8 @@ import (
 	database "github.com/glup3/TrendyGitHub/internal/db"
 	"github.com/glup3/TrendyGitHub/internal/github"
 	"github.com/glup3/TrendyGitHub/internal/jobs"
+	"github.com/glup3/TrendyGitHub/internal/ratelimit"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
 )
 
 func main() {
@@ -18,6 +20,11 @@ func main() {
 		log.Fatalf("error loading configuration: %v", err)
 	}
 
+	err = configs.CheckGitHub()
+	if err != nil {
+		log.Fatalf("error loading configuration: %v", err)
+	}
+
 	db, err := database.NewDatabase(ctx, configs.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v", err)
@@ -29,8 +36,22 @@ func main() {
 		log.Fatalf("Unable to ping database: %v", err)
 	}
 
-	client := github.NewClient(ctx, configs.GitHubToken)
+	err = db.CheckSchemaVersion(ctx)
+	if err != nil {
+		log.Fatalf("Refusing to run: %v", err)
+	}
+
+	tokens, err := tokenpool.New(configs.GitHubTokens)
+	if err != nil {
+		log.Fatalf("Unable to create token pool: %v", err)
+	}
+
+	middleware := github.DefaultMiddleware(tokens, ratelimit.New(ratelimit.DefaultOptions), github.NewBreaker(github.DefaultBreakerOptions))
+	client := github.NewClient(github.Options{Tokens: tokens, QueryRetry: github.DefaultRetryPolicy}, middleware...)
 
-	historyJob := jobs.NewHistoryJob(ctx, db, nil, client)
-	historyJob.Repair()
+	historyJob := jobs.NewHistoryJob(db, nil, client)
+	err = historyJob.Repair(ctx, 1_000_000)
+	if err != nil {
+		log.Fatalf("Repairing failed: %v", err)
+	}
 }
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS trend_rankings (
+    period TEXT NOT NULL,
+    date DATE NOT NULL,
+    rank INT NOT NULL,
+    repository_id INT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    stars_diff INT NOT NULL,
+    PRIMARY KEY (period, date, rank),
+    UNIQUE (period, date, repository_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trend_rankings_repository_id ON trend_rankings(repository_id, period, date);
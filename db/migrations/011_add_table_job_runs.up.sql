@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS job_runs (
+    id SERIAL PRIMARY KEY,
+    mode TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    graphql_units INT NOT NULL DEFAULT 0,
+    rest_units INT NOT NULL DEFAULT 0,
+    repos_processed INT NOT NULL DEFAULT 0,
+    cursor TEXT,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_mode_started_at ON job_runs(mode, started_at DESC);
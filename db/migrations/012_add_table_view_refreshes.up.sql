@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS view_refreshes (
+    view TEXT PRIMARY KEY,
+    refreshed_at TIMESTAMPTZ NOT NULL,
+    duration_ms INT NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS watchlist_repositories;
+DROP TABLE IF EXISTS watchlists;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS watchlists (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS watchlist_repositories (
+    watchlist_id INT NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+    repository_id INT NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (watchlist_id, repository_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_repositories_repository_id ON watchlist_repositories(repository_id);
+
+INSERT INTO watchlists(name) VALUES ('default');
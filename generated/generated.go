@@ -608,6 +608,126 @@ type GetRateLimitResponse struct {
 // GetRateLimit returns GetRateLimitResponse.RateLimit, and is useful for accessing the field via an interface.
 func (v *GetRateLimitResponse) GetRateLimit() GetRateLimitRateLimit { return v.RateLimit }
 
+// GetRepositoryRepository includes the requested fields of the GraphQL type Repository.
+// The GraphQL type's documentation follows.
+//
+// A repository contains the content for a project.
+type GetRepositoryRepository struct {
+	// The Node ID of the Repository object
+	Id string `json:"id"`
+	// Returns a count of how many stargazers there are on this object
+	StargazerCount int `json:"stargazerCount"`
+	// The description of the repository.
+	Description string `json:"description"`
+	// Returns how many forks there are of this repository in the whole network.
+	ForkCount int `json:"forkCount"`
+	// The name of the repository.
+	Name string `json:"name"`
+	// The repository's name with owner.
+	NameWithOwner string `json:"nameWithOwner"`
+	// The primary language of the repository's code.
+	PrimaryLanguage GetRepositoryRepositoryPrimaryLanguage `json:"primaryLanguage"`
+	// A list containing a breakdown of the language composition of the repository.
+	Languages GetRepositoryRepositoryLanguagesLanguageConnection `json:"languages"`
+}
+
+// GetId returns GetRepositoryRepository.Id, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetId() string { return v.Id }
+
+// GetStargazerCount returns GetRepositoryRepository.StargazerCount, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetStargazerCount() int { return v.StargazerCount }
+
+// GetDescription returns GetRepositoryRepository.Description, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetDescription() string { return v.Description }
+
+// GetForkCount returns GetRepositoryRepository.ForkCount, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetForkCount() int { return v.ForkCount }
+
+// GetName returns GetRepositoryRepository.Name, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetName() string { return v.Name }
+
+// GetNameWithOwner returns GetRepositoryRepository.NameWithOwner, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetNameWithOwner() string { return v.NameWithOwner }
+
+// GetPrimaryLanguage returns GetRepositoryRepository.PrimaryLanguage, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetPrimaryLanguage() GetRepositoryRepositoryPrimaryLanguage {
+	return v.PrimaryLanguage
+}
+
+// GetLanguages returns GetRepositoryRepository.Languages, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepository) GetLanguages() GetRepositoryRepositoryLanguagesLanguageConnection {
+	return v.Languages
+}
+
+// GetRepositoryRepositoryLanguagesLanguageConnection includes the requested fields of the GraphQL type LanguageConnection.
+// The GraphQL type's documentation follows.
+//
+// A list of languages associated with the parent.
+type GetRepositoryRepositoryLanguagesLanguageConnection struct {
+	// A list of edges.
+	Edges []GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge `json:"edges"`
+}
+
+// GetEdges returns GetRepositoryRepositoryLanguagesLanguageConnection.Edges, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepositoryLanguagesLanguageConnection) GetEdges() []GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge {
+	return v.Edges
+}
+
+// GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge includes the requested fields of the GraphQL type LanguageEdge.
+// The GraphQL type's documentation follows.
+//
+// Represents the language of a repository.
+type GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge struct {
+	Node GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage `json:"node"`
+}
+
+// GetNode returns GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge.Node, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdge) GetNode() GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage {
+	return v.Node
+}
+
+// GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage includes the requested fields of the GraphQL type Language.
+// The GraphQL type's documentation follows.
+//
+// Represents a given language found in repositories.
+type GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage struct {
+	// The name of the current language.
+	Name string `json:"name"`
+	// The color defined for the current language.
+	Color string `json:"color"`
+}
+
+// GetName returns GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage.Name, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage) GetName() string {
+	return v.Name
+}
+
+// GetColor returns GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage.Color, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepositoryLanguagesLanguageConnectionEdgesLanguageEdgeNodeLanguage) GetColor() string {
+	return v.Color
+}
+
+// GetRepositoryRepositoryPrimaryLanguage includes the requested fields of the GraphQL type Language.
+// The GraphQL type's documentation follows.
+//
+// Represents a given language found in repositories.
+type GetRepositoryRepositoryPrimaryLanguage struct {
+	// The name of the current language.
+	Name string `json:"name"`
+}
+
+// GetName returns GetRepositoryRepositoryPrimaryLanguage.Name, and is useful for accessing the field via an interface.
+func (v *GetRepositoryRepositoryPrimaryLanguage) GetName() string { return v.Name }
+
+// GetRepositoryResponse is returned by GetRepository on success.
+type GetRepositoryResponse struct {
+	// Lookup a given repository by the owner and repository name.
+	Repository GetRepositoryRepository `json:"repository"`
+}
+
+// GetRepository returns GetRepositoryResponse.Repository, and is useful for accessing the field via an interface.
+func (v *GetRepositoryResponse) GetRepository() GetRepositoryRepository { return v.Repository }
+
 // GetStarGazersNode includes the requested fields of the GraphQL interface Node.
 //
 // GetStarGazersNode is implemented by the following types:
@@ -6757,6 +6877,18 @@ func (v *__GetPublicReposInput) GetLimit() int { return v.Limit }
 // GetCursor returns __GetPublicReposInput.Cursor, and is useful for accessing the field via an interface.
 func (v *__GetPublicReposInput) GetCursor() string { return v.Cursor }
 
+// __GetRepositoryInput is used internally by genqlient
+type __GetRepositoryInput struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+// GetOwner returns __GetRepositoryInput.Owner, and is useful for accessing the field via an interface.
+func (v *__GetRepositoryInput) GetOwner() string { return v.Owner }
+
+// GetName returns __GetRepositoryInput.Name, and is useful for accessing the field via an interface.
+func (v *__GetRepositoryInput) GetName() string { return v.Name }
+
 // __GetStarGazersInput is used internally by genqlient
 type __GetStarGazersInput struct {
 	Id     string `json:"id"`
@@ -6878,6 +7010,59 @@ func GetRateLimit(
 	return &data_, err_
 }
 
+// The query or mutation executed by GetRepository.
+const GetRepository_Operation = `
+query GetRepository ($owner: String!, $name: String!) {
+	repository(owner: $owner, name: $name) {
+		id
+		stargazerCount
+		description
+		forkCount
+		name
+		nameWithOwner
+		primaryLanguage {
+			name
+		}
+		languages(first: 100, orderBy: {field:SIZE,direction:DESC}) {
+			edges {
+				node {
+					name
+					color
+				}
+			}
+		}
+	}
+}
+`
+
+func GetRepository(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	owner string,
+	name string,
+) (*GetRepositoryResponse, error) {
+	req_ := &graphql.Request{
+		OpName: "GetRepository",
+		Query:  GetRepository_Operation,
+		Variables: &__GetRepositoryInput{
+			Owner: owner,
+			Name:  name,
+		},
+	}
+	var err_ error
+
+	var data_ GetRepositoryResponse
+	resp_ := &graphql.Response{Data: &data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return &data_, err_
+}
+
 // The query or mutation executed by GetStarGazers.
 const GetStarGazers_Operation = `
 query GetStarGazers ($id: ID!, $cursor: String!) {
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxEntries bounds the cached queries per period, further queries go to the database
+	maxEntries     = 1_000
+	reconnectDelay = 5 * time.Second
+)
+
+type trendLoader interface {
+	GetTrends(ctx context.Context, query repository.TrendQuery) ([]repository.Trend, error)
+	GetLanguages(ctx context.Context, period repository.TrendPeriod) ([]repository.TrendLanguage, error)
+}
+
+// TrendCache keeps trend lists in memory until the view of their period gets refreshed
+type TrendCache struct {
+	loader  trendLoader
+	db      *database.Database
+	periods map[repository.TrendPeriod]*periodCache
+	mu      sync.RWMutex
+}
+
+type periodCache struct {
+	trends    map[repository.TrendQuery]*trendEntry
+	languages *languageEntry
+	// generation counts the refreshes, a load that started before one isn't cached
+	generation int
+}
+
+type trendEntry struct {
+	trends []repository.Trend
+	hit    bool
+}
+
+type languageEntry struct {
+	languages []repository.TrendLanguage
+	hit       bool
+}
+
+func NewTrendCache(db *database.Database, loader trendLoader) *TrendCache {
+	c := &TrendCache{
+		loader:  loader,
+		db:      db,
+		periods: make(map[repository.TrendPeriod]*periodCache),
+	}
+
+	for _, period := range repository.TrendPeriods {
+		c.periods[period] = newPeriodCache(0)
+	}
+
+	return c
+}
+
+func newPeriodCache(generation int) *periodCache {
+	return &periodCache{trends: make(map[repository.TrendQuery]*trendEntry), generation: generation}
+}
+
+// generation returns the generation of a period, -1 for periods that aren't cached
+func (c *TrendCache) generation(period repository.TrendPeriod) int {
+	if cache, ok := c.periods[period]; ok {
+		return cache.generation
+	}
+	return -1
+}
+
+func (c *TrendCache) GetTrends(ctx context.Context, query repository.TrendQuery) ([]repository.Trend, error) {
+	c.mu.Lock()
+	if cache, ok := c.periods[query.Period]; ok {
+		if entry, ok := cache.trends[query]; ok {
+			entry.hit = true
+			c.mu.Unlock()
+			return entry.trends, nil
+		}
+	}
+	generation := c.generation(query.Period)
+	c.mu.Unlock()
+
+	trends, err := c.loader.GetTrends(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if cache, ok := c.periods[query.Period]; ok && cache.generation == generation && len(cache.trends) < maxEntries {
+		cache.trends[query] = &trendEntry{trends: trends, hit: true}
+	}
+	c.mu.Unlock()
+
+	return trends, nil
+}
+
+func (c *TrendCache) GetLanguages(ctx context.Context, period repository.TrendPeriod) ([]repository.TrendLanguage, error) {
+	c.mu.Lock()
+	if cache, ok := c.periods[period]; ok && cache.languages != nil {
+		cache.languages.hit = true
+		c.mu.Unlock()
+		return cache.languages.languages, nil
+	}
+	generation := c.generation(period)
+	c.mu.Unlock()
+
+	languages, err := c.loader.GetLanguages(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if cache, ok := c.periods[period]; ok && cache.generation == generation {
+		cache.languages = &languageEntry{languages: languages, hit: true}
+	}
+	c.mu.Unlock()
+
+	return languages, nil
+}
+
+// Refresh drops all cached queries of a period and re-warms the ones that were
+// requested since the last refresh
+func (c *TrendCache) Refresh(ctx context.Context, period repository.TrendPeriod) {
+	c.mu.Lock()
+	old, ok := c.periods[period]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	c.periods[period] = newPeriodCache(old.generation + 1)
+	c.mu.Unlock()
+
+	warmed := newPeriodCache(old.generation + 1)
+
+	for query, entry := range old.trends {
+		if !entry.hit {
+			continue
+		}
+
+		trends, err := c.loader.GetTrends(ctx, query)
+		if err != nil {
+			log.Warn().Err(err).Str("period", string(period)).Msg("failed re-warming trends")
+			continue
+		}
+		warmed.trends[query] = &trendEntry{trends: trends}
+	}
+
+	if old.languages != nil && old.languages.hit {
+		languages, err := c.loader.GetLanguages(ctx, period)
+		if err != nil {
+			log.Warn().Err(err).Str("period", string(period)).Msg("failed re-warming languages")
+		} else {
+			warmed.languages = &languageEntry{languages: languages}
+		}
+	}
+
+	// warmed entries win, a request could have cached the same query in the meantime.
+	// A newer refresh replaced the period already and warms it itself.
+	c.mu.Lock()
+	current := c.periods[period]
+	if current.generation == warmed.generation {
+		for query, entry := range warmed.trends {
+			if _, exists := current.trends[query]; exists || len(current.trends) < maxEntries {
+				current.trends[query] = entry
+			}
+		}
+		if warmed.languages != nil {
+			current.languages = warmed.languages
+		}
+	}
+	c.mu.Unlock()
+
+	log.Info().
+		Str("period", string(period)).
+		Int("warmed", len(warmed.trends)).
+		Msg("refreshed trend cache")
+}
+
+func (c *TrendCache) RefreshAll(ctx context.Context) {
+	for _, period := range repository.TrendPeriods {
+		c.Refresh(ctx, period)
+	}
+}
+
+// Listen refreshes the cache whenever a trend view got refreshed until ctx is done
+func (c *TrendCache) Listen(ctx context.Context) {
+	for {
+		err := c.db.Listen(ctx, repository.ViewRefreshedChannel, func(view string) {
+			for _, period := range repository.TrendPeriods {
+				if period.View() == view {
+					c.Refresh(ctx, period)
+					return
+				}
+			}
+			log.Warn().Str("view", view).Msg("ignoring refresh of unknown view")
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warn().Err(err).Msgf("lost connection listening for view refreshes - retrying in %s", reconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+
+		// notifications might have been missed while not listening
+		c.RefreshAll(ctx)
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+)
+
+type fakeLoader struct {
+	// loading runs once while the next trends are loaded
+	loading       func()
+	trendCalls    int
+	languageCalls int
+}
+
+func (l *fakeLoader) GetTrends(ctx context.Context, query repository.TrendQuery) ([]repository.Trend, error) {
+	l.trendCalls++
+	trends := []repository.Trend{{Id: l.trendCalls}}
+
+	if loading := l.loading; loading != nil {
+		l.loading = nil
+		loading()
+	}
+
+	return trends, nil
+}
+
+func (l *fakeLoader) GetLanguages(ctx context.Context, period repository.TrendPeriod) ([]repository.TrendLanguage, error) {
+	l.languageCalls++
+	return []repository.TrendLanguage{{Name: "Go", Count: l.languageCalls}}, nil
+}
+
+func TestTrendCache(t *testing.T) {
+	ctx := context.Background()
+	daily := repository.TrendQuery{Period: repository.PeriodDaily, Limit: 25}
+	weekly := repository.TrendQuery{Period: repository.PeriodWeekly, Limit: 25}
+
+	t.Run("Repeated queries are served from memory", func(t *testing.T) {
+		loader := &fakeLoader{}
+		c := NewTrendCache(nil, loader)
+
+		c.GetTrends(ctx, daily)
+		trends, _ := c.GetTrends(ctx, daily)
+
+		if loader.trendCalls != 1 {
+			t.Fatalf("got %d loader calls, want 1", loader.trendCalls)
+		}
+		if trends[0].Id != 1 {
+			t.Fatalf("got trend %d, want 1", trends[0].Id)
+		}
+	})
+
+	t.Run("Refresh re-warms only the refreshed period", func(t *testing.T) {
+		loader := &fakeLoader{}
+		c := NewTrendCache(nil, loader)
+
+		c.GetTrends(ctx, daily)
+		c.GetTrends(ctx, weekly)
+		c.GetLanguages(ctx, repository.PeriodDaily)
+
+		c.Refresh(ctx, repository.PeriodDaily)
+
+		if loader.trendCalls != 3 {
+			t.Fatalf("got %d loader calls, want 3", loader.trendCalls)
+		}
+		if loader.languageCalls != 2 {
+			t.Fatalf("got %d language loader calls, want 2", loader.languageCalls)
+		}
+
+		trends, _ := c.GetTrends(ctx, daily)
+		if trends[0].Id != 3 {
+			t.Fatalf("got trend %d, want re-warmed trend 3", trends[0].Id)
+		}
+
+		trends, _ = c.GetTrends(ctx, weekly)
+		if trends[0].Id != 2 {
+			t.Fatalf("got trend %d, want cached trend 2", trends[0].Id)
+		}
+
+		if loader.trendCalls != 3 {
+			t.Fatalf("got %d loader calls, want 3", loader.trendCalls)
+		}
+	})
+
+	t.Run("Loads that started before a refresh are not cached", func(t *testing.T) {
+		loader := &fakeLoader{}
+		c := NewTrendCache(nil, loader)
+
+		loader.loading = func() { c.Refresh(ctx, repository.PeriodDaily) }
+		c.GetTrends(ctx, daily)
+
+		trends, _ := c.GetTrends(ctx, daily)
+		if trends[0].Id != 2 {
+			t.Fatalf("got trend %d, want trend 2 loaded after the refresh", trends[0].Id)
+		}
+	})
+
+	t.Run("Queries without hits are not re-warmed", func(t *testing.T) {
+		loader := &fakeLoader{}
+		c := NewTrendCache(nil, loader)
+
+		c.GetTrends(ctx, daily)
+		c.Refresh(ctx, repository.PeriodDaily)
+		c.Refresh(ctx, repository.PeriodDaily)
+
+		if loader.trendCalls != 2 {
+			t.Fatalf("got %d loader calls, want 2", loader.trendCalls)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+const (
+	badgeHeight    = 20
+	badgePadding   = 6
+	badgeCharWidth = 7 // average width of an 11px Verdana glyph
+)
+
+// Badge renders a shields.io like badge with a grey label and a colored message
+func Badge(label string, message string, color string) []byte {
+	var b bytes.Buffer
+
+	labelWidth := textWidth(label)
+	messageWidth := textWidth(message)
+	width := labelWidth + messageWidth
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" role="img" aria-label="%s: %s">`, width, badgeHeight, html.EscapeString(label), html.EscapeString(message))
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, html.EscapeString(label), html.EscapeString(message))
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="%d" rx="3" fill="#fff"/></clipPath>`, width, badgeHeight)
+	b.WriteString(`<g clip-path="url(#r)">`)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#555"/>`, labelWidth, badgeHeight)
+	fmt.Fprintf(&b, `<rect x="%d" width="%d" height="%d" fill="%s"/>`, labelWidth, messageWidth, badgeHeight, html.EscapeString(color))
+	b.WriteString(`</g>`)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&b, `<text x="%.1f" y="14">%s</text>`, float64(labelWidth)/2, html.EscapeString(label))
+	fmt.Fprintf(&b, `<text x="%.1f" y="14">%s</text>`, float64(labelWidth)+float64(messageWidth)/2, html.EscapeString(message))
+	b.WriteString(`</g></svg>`)
+
+	return b.Bytes()
+}
+
+func textWidth(text string) int {
+	return len([]rune(text))*badgeCharWidth + 2*badgePadding
+}
@@ -0,0 +1,135 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+	"time"
+)
+
+const (
+	marginTop    = 40
+	marginRight  = 20
+	marginBottom = 40
+	marginLeft   = 60
+	yTicks       = 4
+)
+
+type Point struct {
+	Time  time.Time
+	Value int
+}
+
+type Options struct {
+	Title  string
+	Width  int
+	Height int
+}
+
+// StarHistory renders the points as an SVG line chart, the points have to be sorted by time
+func StarHistory(points []Point, opts Options) []byte {
+	var b bytes.Buffer
+
+	width, height := float64(opts.Width), float64(opts.Height)
+	plotWidth := width - marginLeft - marginRight
+	plotHeight := height - marginTop - marginBottom
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Verdana,Geneva,sans-serif" font-size="11">`, opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#fff"/>`)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="14" fill="#1f2328">%s</text>`, marginLeft, marginTop/2+5, html.EscapeString(opts.Title))
+
+	if len(points) == 0 {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#59636e">no star history</text>`, width/2, height/2)
+		b.WriteString(`</svg>`)
+		return b.Bytes()
+	}
+
+	minValue, maxValue := points[0].Value, points[0].Value
+	for _, p := range points {
+		minValue = min(minValue, p.Value)
+		maxValue = max(maxValue, p.Value)
+	}
+	lower, upper, step := niceRange(minValue, maxValue)
+
+	start, end := points[0].Time, points[len(points)-1].Time
+	duration := end.Sub(start).Seconds()
+
+	x := func(t time.Time) float64 {
+		if duration == 0 {
+			return marginLeft + plotWidth/2
+		}
+		return marginLeft + t.Sub(start).Seconds()/duration*plotWidth
+	}
+	y := func(v int) float64 {
+		return marginTop + plotHeight - float64(v-lower)/float64(upper-lower)*plotHeight
+	}
+
+	for tick := lower; tick <= upper; tick += step {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#d0d7de"/>`, marginLeft, y(tick), width-marginRight, y(tick))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" fill="#59636e">%s</text>`, marginLeft-8, y(tick)+4, FormatCompact(tick))
+	}
+
+	fmt.Fprintf(&b, `<text x="%d" y="%.1f" fill="#59636e">%s</text>`, marginLeft, height-marginBottom/2, start.Format("2006-01-02"))
+	fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#59636e">%s</text>`, width-marginRight, height-marginBottom/2, end.Format("2006-01-02"))
+
+	b.WriteString(`<polyline fill="none" stroke="#0969da" stroke-width="2" stroke-linejoin="round" points="`)
+	for i, p := range points {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x(p.Time), y(p.Value))
+	}
+	b.WriteString(`"/>`)
+
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+// niceRange widens [minValue, maxValue] to round tick boundaries
+func niceRange(minValue, maxValue int) (int, int, int) {
+	span := maxValue - minValue
+	if span == 0 {
+		span = max(1, maxValue/10)
+	}
+
+	rawStep := float64(span) / yTicks
+	magnitude := math.Pow(10, math.Floor(math.Log10(rawStep)))
+	step := magnitude
+	for _, factor := range []float64{1, 2, 5, 10} {
+		step = factor * magnitude
+		if step >= rawStep {
+			break
+		}
+	}
+
+	s := max(1, int(step))
+	lower := int(math.Floor(float64(minValue)/float64(s))) * s
+	upper := int(math.Ceil(float64(maxValue)/float64(s))) * s
+	if upper == lower {
+		upper += s
+	}
+
+	return lower, upper, s
+}
+
+// FormatCompact shortens numbers like 12345 to 12.3k
+func FormatCompact(n int) string {
+	abs := math.Abs(float64(n))
+
+	switch {
+	case abs >= 1_000_000:
+		return trimZero(fmt.Sprintf("%.1f", float64(n)/1_000_000)) + "M"
+	case abs >= 1_000:
+		return trimZero(fmt.Sprintf("%.1f", float64(n)/1_000)) + "k"
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func trimZero(s string) string {
+	if len(s) > 2 && s[len(s)-2:] == ".0" {
+		return s[:len(s)-2]
+	}
+	return s
+}
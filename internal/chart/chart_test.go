@@ -0,0 +1,102 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStarHistory(t *testing.T) {
+	t.Run("Renders a polyline through all points", func(t *testing.T) {
+		points := []Point{
+			{Time: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), Value: 100},
+			{Time: time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC), Value: 150},
+			{Time: time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC), Value: 200},
+		}
+
+		svg := string(StarHistory(points, Options{Title: "glup3/<repo>", Width: 400, Height: 200}))
+
+		if !strings.Contains(svg, `points="60.0,160.0 220.0,100.0 380.0,40.0"`) {
+			t.Errorf("unexpected polyline in %s", svg)
+		}
+		if !strings.Contains(svg, "glup3/&lt;repo&gt;") {
+			t.Errorf("expected escaped title in %s", svg)
+		}
+		if !strings.Contains(svg, "2024-07-01") || !strings.Contains(svg, "2024-07-03") {
+			t.Errorf("expected date labels in %s", svg)
+		}
+	})
+
+	t.Run("Renders a placeholder without points", func(t *testing.T) {
+		svg := string(StarHistory(nil, Options{Width: 400, Height: 200}))
+
+		if !strings.Contains(svg, "no star history") {
+			t.Errorf("expected placeholder in %s", svg)
+		}
+	})
+}
+
+func TestNiceRange(t *testing.T) {
+	tests := []struct {
+		name                 string
+		min, max             int
+		lower, upper, stepBy int
+	}{
+		{name: "Round bounds", min: 100, max: 200, lower: 100, upper: 200, stepBy: 50},
+		{name: "Uneven bounds", min: 13, max: 87, lower: 0, upper: 100, stepBy: 20},
+		{name: "Single value", min: 500, max: 500, lower: 500, upper: 520, stepBy: 20},
+		{name: "Zero", min: 0, max: 0, lower: 0, upper: 1, stepBy: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lower, upper, step := niceRange(tt.min, tt.max)
+			if lower != tt.lower || upper != tt.upper || step != tt.stepBy {
+				t.Errorf("got (%d, %d, %d), want (%d, %d, %d)", lower, upper, step, tt.lower, tt.upper, tt.stepBy)
+			}
+		})
+	}
+}
+
+func TestFormatCompact(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1k", 12345: "12.3k", 2_500_000: "2.5M", -1500: "-1.5k"}
+
+	for input, expected := range tests {
+		if got := FormatCompact(input); got != expected {
+			t.Errorf("FormatCompact(%d) = %s, want %s", input, got, expected)
+		}
+	}
+}
+
+func TestBadge(t *testing.T) {
+	svg := string(Badge("stars", "1.2k | +34/week", "#1a7f37"))
+
+	if !strings.Contains(svg, `aria-label="stars: 1.2k | +34/week"`) {
+		t.Errorf("unexpected badge %s", svg)
+	}
+	if !strings.Contains(svg, `fill="#1a7f37"`) {
+		t.Errorf("expected message color in %s", svg)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []int
+		width    int
+		expected string
+	}{
+		{name: "empty", values: nil, width: 10, expected: ""},
+		{name: "flat", values: []int{5, 5, 5}, width: 10, expected: "▁▁▁"},
+		{name: "scaled", values: []int{0, 7, 14}, width: 10, expected: "▁▄█"},
+		{name: "downsampled", values: []int{0, 1, 2, 3, 4, 5, 6, 7}, width: 4, expected: "▁▃▅█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values, tt.width); got != tt.expected {
+				t.Errorf("Sparkline(%v, %d) = %s, want %s", tt.values, tt.width, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package chart
+
+import "strings"
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a line of unicode bars, longer series get
+// downsampled to width by keeping the last value of every chunk
+func Sparkline(values []int, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	if len(values) > width {
+		sampled := make([]int, width)
+		for i := range sampled {
+			sampled[i] = values[(i+1)*len(values)/width-1]
+		}
+		values = sampled
+	}
+
+	lowest, highest := values[0], values[0]
+	for _, v := range values {
+		lowest = min(lowest, v)
+		highest = max(highest, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if highest > lowest {
+			level = (v - lowest) * (len(sparkBars) - 1) / (highest - lowest)
+		}
+		b.WriteRune(sparkBars[level])
+	}
+
+	return b.String()
+}
@@ -3,37 +3,126 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	GitHubToken  string
-	GitHubToken2 string
+	// GitHubTokens are GITHUB_TOKEN followed by the comma separated GITHUB_TOKENS
+	GitHubTokens []string
 	DatabaseURL  string
+	ServerAddr   string
+
+	// GitHubRecordDir records every GitHub response, GitHubReplayDir answers the requests from such a recording
+	GitHubRecordDir   string
+	GitHubReplayDir   string
+	LogGitHubRequests bool
+
+	// APIKeys are the comma separated API_KEYS, each gets a rate limit of its own
+	APIKeys            []string
+	RateLimitBurst     int
+	RateLimitPerMinute int
 }
 
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
-	gitHubToken := os.Getenv("GITHUB_TOKEN")
-	if gitHubToken == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN must be set")
+	gitHubRecordDir := os.Getenv("GITHUB_RECORD_DIR")
+	gitHubReplayDir := os.Getenv("GITHUB_REPLAY_DIR")
+	if gitHubRecordDir != "" && gitHubReplayDir != "" {
+		return nil, fmt.Errorf("GITHUB_RECORD_DIR and GITHUB_REPLAY_DIR can't be set both")
 	}
 
-	gitHubToken2 := os.Getenv("GITHUB_TOKEN")
-	if gitHubToken2 == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN must be set")
-	}
+	gitHubTokens := parseTokens(os.Getenv("GITHUB_TOKEN") + "," + os.Getenv("GITHUB_TOKENS"))
 
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL must be set")
 	}
 
+	serverAddr := os.Getenv("SERVER_ADDR")
+	if serverAddr == "" {
+		serverAddr = ":8080"
+	}
+
+	rateLimitBurst, err := getEnvPositiveInt("RATE_LIMIT_BURST", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitPerMinute, err := getEnvPositiveInt("RATE_LIMIT_PER_MINUTE", 60)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		GitHubToken:  gitHubToken,
-		GitHubToken2: gitHubToken2,
-		DatabaseURL:  databaseURL,
+		GitHubTokens:       gitHubTokens,
+		DatabaseURL:        databaseURL,
+		ServerAddr:         serverAddr,
+		GitHubRecordDir:    gitHubRecordDir,
+		GitHubReplayDir:    gitHubReplayDir,
+		LogGitHubRequests:  os.Getenv("GITHUB_LOG_REQUESTS") == "true",
+		APIKeys:            parseTokens(os.Getenv("API_KEYS")),
+		RateLimitBurst:     rateLimitBurst,
+		RateLimitPerMinute: rateLimitPerMinute,
 	}, nil
 }
+
+// HasGitHub reports whether GitHub can be reached, a replay doesn't send any requests to it
+func (c *Config) HasGitHub() bool {
+	return len(c.GitHubTokens) > 0 || c.GitHubReplayDir != ""
+}
+
+// CheckGitHub fails without tokens, only commands that talk to GitHub need them
+func (c *Config) CheckGitHub() error {
+	if !c.HasGitHub() {
+		return fmt.Errorf("GITHUB_TOKEN or GITHUB_TOKENS must be set")
+	}
+	return nil
+}
+
+// parseTokens splits a comma separated list of tokens and drops empty and duplicate ones
+func parseTokens(value string) []string {
+	tokens := []string{}
+	seen := make(map[string]bool)
+
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+
+	return i, nil
+}
+
+func getEnvPositiveInt(key string, fallback int) (int, error) {
+	i, err := getEnvInt(key, fallback)
+	if err != nil {
+		return 0, err
+	}
+
+	if i <= 0 {
+		return 0, fmt.Errorf("%s must be greater than 0", key)
+	}
+
+	return i, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -43,3 +44,30 @@ func (db *Database) Ping(ctx context.Context) error {
 func (db *Database) Close() {
 	db.Pool.Close()
 }
+
+// Listen blocks and calls handle with the payload of every notification on channel
+// until ctx is done or the connection breaks
+func (db *Database) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	poolConn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to acquire connection: %w", err)
+	}
+
+	// the connection is never given back to the pool because it keeps listening
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", channel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle(notification.Payload)
+	}
+}
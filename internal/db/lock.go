@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrLockHeld = errors.New("lock is held by another session")
+
+// Lock is a session level advisory lock, it holds on to its connection until unlocked
+// and is released by Postgres when the process dies
+type Lock struct {
+	conn *pgxpool.Conn
+	Name string
+	key  int64
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// TryLock returns ErrLockHeld if another session holds the lock
+func (db *Database) TryLock(ctx context.Context, name string) (*Lock, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire connection: %w", err)
+	}
+
+	key := lockKey(name)
+
+	var locked bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked)
+	if err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("unable to lock %s: %w", name, err)
+	}
+
+	if !locked {
+		conn.Release()
+		return nil, ErrLockHeld
+	}
+
+	return &Lock{conn: conn, Name: name, key: key}, nil
+}
+
+// Lock blocks until the lock is free or ctx is done
+func (db *Database) Lock(ctx context.Context, name string) (*Lock, error) {
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire connection: %w", err)
+	}
+
+	key := lockKey(name)
+
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", key)
+	if err != nil {
+		// a cancelled wait can leave the connection in an unknown state
+		conn.Conn().Close(context.Background())
+		conn.Release()
+		return nil, fmt.Errorf("unable to lock %s: %w", name, err)
+	}
+
+	return &Lock{conn: conn, Name: name, key: key}, nil
+}
+
+func (l *Lock) Unlock(ctx context.Context) error {
+	defer l.conn.Release()
+
+	_, err := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		// closing the session releases the lock as well
+		l.conn.Conn().Close(context.Background())
+		return fmt.Errorf("unable to unlock %s: %w", l.Name, err)
+	}
+
+	return nil
+}
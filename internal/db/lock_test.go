@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLock(t *testing.T) {
+	connString, cleanup, restore, err := testutil.SetupPostgresContainer()
+	if err != nil {
+		t.Fatalf("failed to set up test container: %v", err)
+	}
+	defer cleanup()
+
+	t.Run("Test second try lock is rejected until unlocked", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &Database{Pool: pool}
+
+		lock, err := db.TryLock(ctx, "search")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = db.TryLock(ctx, "search")
+		if !errors.Is(err, ErrLockHeld) {
+			t.Fatalf("Expected %v to be ErrLockHeld", err)
+		}
+
+		other, err := db.TryLock(ctx, "refresh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		other.Unlock(ctx)
+
+		err = lock.Unlock(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lock, err = db.TryLock(ctx, "search")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.Unlock(ctx)
+	})
+
+	t.Run("Test waiting for a lock", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &Database{Pool: pool}
+
+		lock, err := db.Lock(ctx, "search")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		_, err = db.Lock(timeoutCtx, "search")
+		if err == nil {
+			t.Fatal("Expected waiting for a held lock to time out")
+		}
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			lock.Unlock(ctx)
+		}()
+
+		lock, err = db.Lock(ctx, "search")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock.Unlock(ctx)
+	})
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/glup3/TrendyGitHub/db/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// undefinedTable is the postgres error code of a missing schema_migrations table
+const undefinedTable = "42P01"
+
+// ErrSchemaVersion is returned by CheckSchemaVersion if the migrations of the binary and the database differ
+var ErrSchemaVersion = errors.New("unexpected schema version")
+
+// NewMigrate applies the embedded migrations to the database at databaseURL
+func NewMigrate(databaseURL string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("creating migrate instance: %w", err)
+	}
+
+	return m, nil
+}
+
+// LatestVersion returns the version of the newest embedded migration
+func LatestVersion() (uint, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return 0, fmt.Errorf("reading embedded migrations: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("reading first migration: %w", err)
+	}
+
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
+
+// SchemaVersion reads the version golang-migrate recorded, 0 if no migration ran yet
+func (db *Database) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := db.Pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTable {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
+	}
+
+	return uint(version), dirty, nil
+}
+
+// CheckSchemaVersion makes sure the database was migrated to exactly the embedded migrations
+func (db *Database) CheckSchemaVersion(ctx context.Context) error {
+	expected, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: migration %d failed halfway and left the schema dirty", ErrSchemaVersion, version)
+	}
+
+	if version > expected {
+		return fmt.Errorf("%w: database is at %d, this binary only knows migrations up to %d", ErrSchemaVersion, version, expected)
+	}
+
+	if version < expected {
+		return fmt.Errorf("%w: database is at %d, expected %d - run tgh migrate up", ErrSchemaVersion, version, expected)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glup3/TrendyGitHub/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	connString, cleanup, restore, err := testutil.SetupPostgresContainer()
+	if err != nil {
+		t.Fatalf("failed to set up test container: %v", err)
+	}
+	defer cleanup()
+
+	t.Run("Test migrated database passes the check", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &Database{Pool: pool}
+
+		err = db.CheckSchemaVersion(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Test outdated database fails the check", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &Database{Pool: pool}
+
+		_, err = pool.Exec(ctx, "UPDATE schema_migrations SET version = 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = db.CheckSchemaVersion(ctx)
+		if !errors.Is(err, ErrSchemaVersion) {
+			t.Fatalf("Expected %v to be ErrSchemaVersion", err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type Feed struct {
+	Updated  time.Time
+	ID       string
+	Title    string
+	Link     string
+	SelfLink string
+	Entries  []Entry
+}
+
+type Entry struct {
+	Updated time.Time
+	// ID must stay the same for the same entry, otherwise feed readers announce it again
+	ID      string
+	Title   string
+	Link    string
+	Summary string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func (f Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Link},
+			{Href: f.SelfLink, Rel: "self"},
+		},
+	}
+
+	for _, entry := range f.Entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      entry.ID,
+			Title:   entry.Title,
+			Updated: entry.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: entry.Link},
+			Summary: entry.Summary,
+		})
+	}
+
+	return marshal(feed)
+}
+
+func (f Feed) RSS() ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Title,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, entry := range f.Entries {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Summary,
+			GUID:        rssGUID{Value: entry.ID},
+			PubDate:     entry.Updated.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return marshal(feed)
+}
+
+func marshal(v any) ([]byte, error) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
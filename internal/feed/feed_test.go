@@ -0,0 +1,57 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeed(t *testing.T) {
+	updated := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	f := Feed{
+		ID:       "urn:trendygithub:feed:daily",
+		Title:    "Daily trends",
+		Link:     "https://example.com/",
+		SelfLink: "https://example.com/feeds/daily.atom",
+		Updated:  updated,
+		Entries: []Entry{
+			{ID: "urn:trendygithub:trend:daily:2024-07-01:1", Title: "glup3/TrendyGitHub & co", Link: "https://github.com/glup3/TrendyGitHub", Updated: updated},
+		},
+	}
+
+	t.Run("Atom", func(t *testing.T) {
+		out, err := f.Atom()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []string{
+			`<feed xmlns="http://www.w3.org/2005/Atom">`,
+			`<id>urn:trendygithub:trend:daily:2024-07-01:1</id>`,
+			`<title>glup3/TrendyGitHub &amp; co</title>`,
+			`<updated>2024-07-01T00:00:00Z</updated>`,
+			`<link href="https://example.com/feeds/daily.atom" rel="self"></link>`,
+		} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("expected atom feed to contain %s", want)
+			}
+		}
+	})
+
+	t.Run("RSS", func(t *testing.T) {
+		out, err := f.RSS()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []string{
+			`<rss version="2.0">`,
+			`<guid isPermaLink="false">urn:trendygithub:trend:daily:2024-07-01:1</guid>`,
+			`<pubDate>Mon, 01 Jul 2024 00:00:00 +0000</pubDate>`,
+		} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("expected rss feed to contain %s", want)
+			}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/rs/zerolog/log"
+)
+
+// secondaryRetry is the reset of a secondary rate limit without a Retry-After header,
+// GitHub asks for at least a minute. The limiter and the retries both wait for it.
+const secondaryRetry = time.Minute
+
+// queryTimeout is how GitHub answers a GraphQL query that timed out, with a 200
+const queryTimeout = "Something went wrong while executing your query"
+
+// Kind classifies why a GitHub request failed
+type Kind string
+
+const (
+	// NotFound repositories were deleted or renamed
+	NotFound Kind = "not found"
+	// LegallyBlocked repositories were taken down, e.g. by a DMCA notice
+	LegallyBlocked       Kind = "legally blocked"
+	SecondaryRateLimited Kind = "secondary rate limited"
+	PrimaryRateLimited   Kind = "primary rate limited"
+	// Transient failures are server errors and broken connections worth retrying
+	Transient    Kind = "transient"
+	Unauthorized Kind = "unauthorized"
+	Unknown      Kind = "unknown"
+)
+
+// Error is a failed GitHub request, jobs branch on its Kind with errors.As
+type Error struct {
+	// ResetAt is when the rate limit allows requests again, zero if GitHub didn't tell
+	ResetAt    time.Time
+	Err        error
+	Kind       Kind
+	Status     string
+	Message    string
+	StatusCode int
+}
+
+func (e *Error) Error() string {
+	msg := "github: " + string(e.Kind)
+	if e.Status != "" {
+		msg += " (" + e.Status + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if !e.ResetAt.IsZero() {
+		msg += ", reset at " + e.ResetAt.Format(time.RFC3339)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Gone is true for repositories that don't exist on GitHub anymore
+func (e *Error) Gone() bool {
+	return e.Kind == NotFound || e.Kind == LegallyBlocked
+}
+
+// RateLimited is true for primary and secondary rate limits
+func (e *Error) RateLimited() bool {
+	return e.Kind == PrimaryRateLimited || e.Kind == SecondaryRateLimited
+}
+
+// Do sends req and turns broken connections and non-2xx responses into an *Error.
+// The body of a failed response is closed.
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, &Error{Kind: Transient, Err: err}
+	}
+
+	err = FromResponse(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// FromResponse classifies a non-2xx response, it reads the body but doesn't close it
+func FromResponse(resp *http.Response) error {
+	return fromResponse(resp, time.Now())
+}
+
+// Peek classifies a failed response like FromResponse and leaves its body readable for
+// the caller. Secondary rate limits without a Retry-After reset secondaryRetry after now.
+func Peek(resp *http.Response, now time.Time) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+
+	clone := *resp
+	clone.Body = io.NopCloser(bytes.NewReader(raw))
+	return fromResponse(&clone, now)
+}
+
+func fromResponse(resp *http.Response, now time.Time) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	e := &Error{
+		Kind:       Unknown,
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Message:    readMessage(resp.Body),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		e.Kind = Unauthorized
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		e.Kind = NotFound
+	case resp.StatusCode == http.StatusUnavailableForLegalReasons:
+		e.Kind = LegallyBlocked
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		classifyForbidden(e, resp.Header, now)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		e.Kind = Transient
+	}
+
+	return e
+}
+
+// classifyForbidden tells rate limits apart from missing permissions,
+// GitHub answers both with 403
+func classifyForbidden(e *Error, header http.Header, now time.Time) {
+	message := strings.ToLower(e.Message)
+
+	if retryAfter, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		e.Kind = SecondaryRateLimited
+		e.ResetAt = now.Add(time.Duration(retryAfter) * time.Second)
+		return
+	}
+
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		e.Kind = PrimaryRateLimited
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			e.ResetAt = time.Unix(reset, 0)
+		}
+		return
+	}
+
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests,
+		strings.Contains(message, "secondary rate limit"),
+		strings.Contains(message, "abuse"):
+		e.Kind = SecondaryRateLimited
+		e.ResetAt = now.Add(secondaryRetry)
+	case strings.Contains(message, "access blocked"):
+		e.Kind = LegallyBlocked
+	default:
+		e.Kind = Unauthorized
+	}
+}
+
+// readMessage returns the message of a GitHub error body, or the body itself
+func readMessage(body io.Reader) string {
+	raw, err := io.ReadAll(io.LimitReader(body, 4096))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &payload) == nil && payload.Message != "" {
+		return payload.Message
+	}
+
+	return strings.TrimSpace(string(raw))
+}
+
+// fromGraphQL classifies the errors GitHub returns next to a 200, they only differ by message
+func fromGraphQL(err error, now time.Time) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "Could not resolve to a"):
+		return &Error{Kind: NotFound, Err: err}
+	case strings.Contains(message, "Unavailable For Legal Reasons"),
+		strings.Contains(message, "access blocked"):
+		return &Error{Kind: LegallyBlocked, Err: err}
+	case strings.Contains(message, "secondary rate limit"):
+		return &Error{Kind: SecondaryRateLimited, Err: err, ResetAt: now.Add(secondaryRetry)}
+	case strings.Contains(message, "API rate limit exceeded"):
+		return &Error{Kind: PrimaryRateLimited, Err: err}
+	case strings.Contains(message, queryTimeout):
+		return &Error{Kind: Transient, Err: err}
+	}
+
+	return err
+}
+
+// NewGraphQLClient is a genqlient client whose failures are classified like the ones of Do.
+// Queries that timed out are retried with policy, the transport never sees them fail.
+func NewGraphQLClient(endpoint string, httpClient *http.Client, policy RetryPolicy) graphql.Client {
+	return &graphqlClient{wrapped: graphql.NewClient(endpoint, &doer{client: httpClient}), policy: policy}
+}
+
+type graphqlClient struct {
+	wrapped graphql.Client
+	policy  RetryPolicy
+}
+
+func (c *graphqlClient) MakeRequest(ctx context.Context, req *graphql.Request, resp *graphql.Response) error {
+	for attempt := 1; ; attempt++ {
+		// the errors of the last attempt would survive a response without any
+		resp.Errors = nil
+
+		err := c.wrapped.MakeRequest(ctx, req, resp)
+		if err == nil {
+			return nil
+		}
+
+		// failed responses are already classified and retried by the transport
+		var ghErr *Error
+		timedOut := !errors.As(err, &ghErr) && strings.Contains(err.Error(), queryTimeout)
+
+		err = fromGraphQL(err, time.Now())
+		if !timedOut || attempt >= c.policy.Attempts {
+			return err
+		}
+
+		delay := c.policy.backoff(attempt)
+		log.Warn().
+			Err(err).
+			Str("operation", req.OpName).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("retrying timed out GraphQL query")
+
+		err = Sleep(ctx, delay)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type doer struct {
+	client *http.Client
+}
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	return Do(d.client, req)
+}
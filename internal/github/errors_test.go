@@ -0,0 +1,171 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Khan/genqlient/graphql"
+)
+
+func TestFromResponse(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    string
+		want    Kind
+	}{
+		{name: "ok", status: http.StatusOK, want: ""},
+		{name: "deleted", status: http.StatusNotFound, body: `{"message":"Not Found"}`, want: NotFound},
+		{name: "dmca", status: http.StatusUnavailableForLegalReasons, want: LegallyBlocked},
+		{name: "blocked", status: http.StatusForbidden, body: `{"message":"Repository access blocked"}`, want: LegallyBlocked},
+		{name: "bad token", status: http.StatusUnauthorized, want: Unauthorized},
+		{name: "no permission", status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "10"}, want: Unauthorized},
+		{name: "server error", status: http.StatusBadGateway, want: Transient},
+		{name: "bad request", status: http.StatusUnprocessableEntity, want: Unknown},
+		{
+			name:    "primary",
+			status:  http.StatusForbidden,
+			headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(resetAt.Unix(), 10)},
+			want:    PrimaryRateLimited,
+		},
+		{name: "retry after", status: http.StatusForbidden, headers: map[string]string{"Retry-After": "30"}, want: SecondaryRateLimited},
+		{name: "secondary", status: http.StatusForbidden, body: `{"message":"You have exceeded a secondary rate limit."}`, want: SecondaryRateLimited},
+		{name: "too many", status: http.StatusTooManyRequests, want: SecondaryRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Status:     http.StatusText(tt.status),
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+
+			err := FromResponse(resp)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var ghErr *Error
+			if !errors.As(err, &ghErr) {
+				t.Fatalf("Expected a classified error, got %v", err)
+			}
+			if ghErr.Kind != tt.want {
+				t.Fatalf("Expected %s to equal %s", ghErr.Kind, tt.want)
+			}
+			if ghErr.RateLimited() && ghErr.ResetAt.IsZero() {
+				t.Fatal("Expected rate limit to carry its reset")
+			}
+			if tt.want == PrimaryRateLimited && !ghErr.ResetAt.Equal(resetAt) {
+				t.Fatalf("Expected %s to equal %s", ghErr.ResetAt, resetAt)
+			}
+		})
+	}
+}
+
+func TestFromGraphQL(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]Kind{
+		"input:3: node Could not resolve to a node with the global id of 'R_kg'": NotFound,
+		"Repository access blocked":                                                            LegallyBlocked,
+		"You have exceeded a secondary rate limit":                                             SecondaryRateLimited,
+		"API rate limit exceeded for user ID 1.":                                               PrimaryRateLimited,
+		"Something went wrong while executing your query. This may be the result of a timeout": Transient,
+	}
+
+	for message, want := range tests {
+		var ghErr *Error
+		if !errors.As(fromGraphQL(errors.New(message), now), &ghErr) || ghErr.Kind != want {
+			t.Fatalf("Expected %q to be classified as %s", message, want)
+		}
+	}
+
+	err := errors.New("Field 'foo' doesn't exist on type 'Query'")
+	if got := fromGraphQL(err, now); got != err {
+		t.Fatalf("Expected %v to stay unclassified", got)
+	}
+}
+
+func TestDoClassifiesResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	client := server.Client()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Do(client, req)
+	var ghErr *Error
+	if !errors.As(err, &ghErr) || !ghErr.Gone() {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+
+	// a closed server is a broken connection
+	server.Close()
+
+	_, err = Do(client, req)
+	if !errors.As(err, &ghErr) || ghErr.Kind != Transient {
+		t.Fatalf("Expected a transient error, got %v", err)
+	}
+
+	// a cancelled request is not GitHub's fault
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = Do(client, req.WithContext(ctx))
+	if errors.As(err, &ghErr) {
+		t.Fatalf("Expected an unclassified error, got %v", err)
+	}
+}
+
+func TestGraphQLClientRetriesTimeouts(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`{"errors":[{"message":"Something went wrong while executing your query. This may be the result of a timeout"}]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"viewer":{"login":"glup3"}}}`))
+	}))
+	defer server.Close()
+
+	client := NewGraphQLClient(server.URL, server.Client(), testRetryPolicy)
+
+	var data struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+	}
+	err := client.MakeRequest(context.Background(), &graphql.Request{Query: "{ viewer { login } }"}, &graphql.Response{Data: &data})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Viewer.Login != "glup3" || calls.Load() != 2 {
+		t.Fatalf("Expected %q after 2 calls, got %d", data.Viewer.Login, calls.Load())
+	}
+}
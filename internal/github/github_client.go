@@ -3,45 +3,109 @@ package github
 import (
 	"context"
 	"net/http"
+	"strings"
 
 	"github.com/Khan/genqlient/graphql"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
 )
 
-const apiUrl = "https://api.github.com"
+const DefaultBaseURL = "https://api.github.com"
 
+// Middleware wraps the transport of one API. The API tells GraphQL and REST requests
+// apart, e.g. for the token budgets and the rate limiter.
+type Middleware func(api tokenpool.API, next http.RoundTripper) http.RoundTripper
+
+type Options struct {
+	// Tokens are iterated by the rate limit checks, the Auth middleware sends them
+	Tokens *tokenpool.Pool
+	// BaseURL defaults to DefaultBaseURL
+	BaseURL string
+	// Transport sends the requests at the end of the chain, defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// QueryRetry retries GraphQL queries that timed out, the zero value doesn't retry
+	QueryRetry RetryPolicy
+}
+
+// GithubClient is the one client of the GraphQL and REST API, every request of the
+// loader and the jobs goes through its middleware chain
 type GithubClient struct {
-	rest    http.Client
+	tokens  *tokenpool.Pool
 	graphql graphql.Client
-	ctx     context.Context
+	rest    http.Client
+	baseURL string
 }
 
-type authedTransport struct {
-	wrapped      http.RoundTripper
-	apiKey       string
-	acceptHeader string
-}
+// NewClient chains the middleware around the transport, the first one is the outermost
+func NewClient(opts Options, middleware ...Middleware) *GithubClient {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
 
-func NewClient(ctx context.Context, apiKey string) *GithubClient {
-	httpClient := http.Client{
-		Transport: &authedTransport{
-			apiKey:       apiKey,
-			acceptHeader: "application/vnd.github.star+json", // required for star history
-			wrapped:      http.DefaultTransport,
-		},
+	chain := func(api tokenpool.API) http.RoundTripper {
+		transport := opts.Transport
+		for i := len(middleware) - 1; i >= 0; i-- {
+			transport = middleware[i](api, transport)
+		}
+		return transport
 	}
 
-	gqlClient := graphql.NewClient(apiUrl+"/graphql", &httpClient)
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+	graphqlClient := http.Client{Transport: chain(tokenpool.GraphQL)}
 
 	return &GithubClient{
-		ctx:     ctx,
-		rest:    httpClient,
-		graphql: gqlClient,
+		tokens:  opts.Tokens,
+		graphql: NewGraphQLClient(baseURL+"/graphql", &graphqlClient, opts.QueryRetry),
+		rest:    http.Client{Transport: chain(tokenpool.REST)},
+		baseURL: baseURL,
+	}
+}
+
+// DefaultMiddleware retries, authenticates and paces the requests, in that order
+func DefaultMiddleware(tokens *tokenpool.Pool, limiter Pacer, breaker *Breaker) []Middleware {
+	return []Middleware{
+		Retry(DefaultRetryPolicy, breaker),
+		Auth(tokens),
+		Pace(limiter),
+	}
+}
+
+// GraphQL is the genqlient client for the generated queries
+func (client *GithubClient) GraphQL() graphql.Client {
+	return client.graphql
+}
+
+// Get sends a REST request for path, relative to the base URL. Failures are an *Error.
+func (client *GithubClient) Get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", client.baseURL+path, nil)
+	if err != nil {
+		return nil, err
 	}
+
+	return Do(&client.rest, req)
+}
+
+// Budget is what the responses told about the remaining points of api over all tokens
+func (client *GithubClient) Budget(api tokenpool.API) tokenpool.Budget {
+	return client.tokens.Total(api)
+}
+
+type unmeteredKey struct{}
+
+// Unmetered lets the requests of ctx skip the pacing, e.g. checking the rate limit
+// must not wait for the reset of an exhausted token
+func Unmetered(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unmeteredKey{}, true)
+}
+
+// IsUnmetered tells a Pacer to let the requests of ctx through
+func IsUnmetered(ctx context.Context) bool {
+	return ctx.Value(unmeteredKey{}) != nil
 }
 
-func (t *authedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", "bearer "+t.apiKey)
-	req.Header.Set("Accept", t.acceptHeader)
-	req.Header.Set("X-Github-Next-Global-ID", "1")
-	return t.wrapped.RoundTrip(req)
+// ForEachToken calls fn once per token of the pool, see tokenpool.Pool.ForEach
+func (client *GithubClient) ForEachToken(ctx context.Context, fn func(ctx context.Context) error) error {
+	return client.tokens.ForEach(ctx, fn)
 }
@@ -1,9 +1,9 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/glup3/TrendyGitHub/generated"
@@ -38,56 +38,54 @@ type stargazer struct {
 	StarredAt time.Time `json:"starred_at"`
 }
 
-func (client GithubClient) GetRateLimit() (RateLimit, error) {
-	req, err := http.NewRequest("GET", apiUrl+"/rate_limit", nil)
+// GetRateLimit sums up the budgets of all tokens, the resets are the earliest ones
+func (client GithubClient) GetRateLimit(ctx context.Context) (RateLimit, error) {
+	var total RateLimit
+
+	err := client.ForEachToken(ctx, func(ctx context.Context) error {
+		resp, err := client.Get(Unmetered(ctx), "/rate_limit")
+		if err != nil {
+			return fmt.Errorf("failed to get rate limit: %w", err)
+		}
+		defer resp.Body.Close()
+
+		var rl rateLimit
+		err = json.NewDecoder(resp.Body).Decode(&rl)
+		if err != nil {
+			return err
+		}
+
+		total.RemainingRest += rl.Rate.Remaining
+		total.RemainingGraphql += rl.Resources.Graphql.Remaining
+		total.ResetRest = earliestReset(total.ResetRest, rl.Rate.Reset)
+		total.ResetGraphql = earliestReset(total.ResetGraphql, rl.Resources.Graphql.Reset)
+
+		return nil
+	})
 	if err != nil {
 		return RateLimit{}, err
 	}
 
-	resp, err := client.rest.Do(req)
-	if err != nil {
-		return RateLimit{}, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return RateLimit{}, err
-	}
+	return total, nil
+}
 
-	var rl rateLimit
-	err = json.NewDecoder(resp.Body).Decode(&rl)
-	if err != nil {
-		return RateLimit{}, err
+func earliestReset(current int, reset int) int {
+	if current == 0 || reset < current {
+		return reset
 	}
-
-	return RateLimit{
-		RemainingRest:    rl.Rate.Remaining,
-		RemainingGraphql: rl.Resources.Graphql.Remaining,
-		ResetRest:        rl.Rate.Reset,
-		ResetGraphql:     rl.Resources.Graphql.Reset,
-	}, nil
+	return current
 }
 
 // only works for repositories with less than 40k stars because of the hardlimit of max. 400 pages
-func (client GithubClient) GetStarHistory(repoFullName string, page int) ([]time.Time, error) {
+func (client GithubClient) GetStarHistory(ctx context.Context, repoFullName string, page int) ([]time.Time, error) {
 	var times []time.Time
 
-	url := fmt.Sprintf("%s/repos/%s/stargazers?page=%d&per_page=100", apiUrl, repoFullName, page)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return times, err
-	}
-
-	resp, err := client.rest.Do(req)
+	resp, err := client.Get(ctx, fmt.Sprintf("/repos/%s/stargazers?page=%d&per_page=100", repoFullName, page))
 	if err != nil {
 		return times, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return times, err
-	}
-
 	var stargazers []stargazer
 	err = json.NewDecoder(resp.Body).Decode(&stargazers)
 	if err != nil {
@@ -102,11 +100,11 @@ func (client GithubClient) GetStarHistory(repoFullName string, page int) ([]time
 }
 
 // uses the graphql api to bypass the 40k stars limit of the REST api
-func (client GithubClient) GetStarHistoryV2(nodeId string, cursor string) ([]time.Time, string, error) {
+func (client GithubClient) GetStarHistoryV2(ctx context.Context, nodeId string, cursor string) ([]time.Time, string, error) {
 	nextCursor := "END"
 	times := make([]time.Time, 100)
 
-	resp, err := generated.GetStarGazers(client.ctx, client.graphql, nodeId, cursor)
+	resp, err := generated.GetStarGazers(ctx, client.graphql, nodeId, cursor)
 	if err != nil {
 		return times, nextCursor, err
 	}
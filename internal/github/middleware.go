@@ -0,0 +1,223 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+	"github.com/rs/zerolog/log"
+)
+
+// roundTripperFunc turns a function into an http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Auth sends the requests with a token of the pool
+func Auth(tokens *tokenpool.Pool) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		acceptHeader := "application/json"
+		if api == tokenpool.REST {
+			acceptHeader = "application/vnd.github.star+json" // required for star history
+		}
+		return tokens.Transport(api, acceptHeader, next)
+	}
+}
+
+// Pacer spaces the requests of an API, see ratelimit.Limiter
+type Pacer interface {
+	Transport(api tokenpool.API, wrapped http.RoundTripper) http.RoundTripper
+}
+
+// Pace spaces the requests with the limiter, it needs the Authorization header set by Auth
+func Pace(limiter Pacer) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return limiter.Transport(api, next)
+	}
+}
+
+// Retry retries transient failures, see RetryTransport
+func Retry(policy RetryPolicy, breaker *Breaker) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return RetryTransport(policy, breaker, next)
+	}
+}
+
+// Log logs every request at debug level
+func Log() Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			event := log.Debug().
+				Str("api", string(api)).
+				Str("method", req.Method).
+				Str("url", req.URL.String()).
+				Dur("duration", time.Since(start))
+			if resp != nil {
+				event = event.Int("status", resp.StatusCode)
+			}
+			event.Err(err).Msg("GitHub request")
+
+			return resp, err
+		})
+	}
+}
+
+// APIMetrics are the totals of one API, a retried call counts once per attempt
+type APIMetrics struct {
+	Requests int
+	// Failures are broken connections and responses with a status of 400 or above
+	Failures int
+	Duration time.Duration
+}
+
+// Metrics counts the requests of a process per API
+type Metrics struct {
+	apis map[tokenpool.API]*APIMetrics
+	mu   sync.Mutex
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{apis: make(map[tokenpool.API]*APIMetrics)}
+}
+
+func (m *Metrics) observe(api tokenpool.API, failed bool, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals, ok := m.apis[api]
+	if !ok {
+		totals = &APIMetrics{}
+		m.apis[api] = totals
+	}
+
+	totals.Requests++
+	totals.Duration += duration
+	if failed {
+		totals.Failures++
+	}
+}
+
+// Snapshot returns a copy of the totals
+func (m *Metrics) Snapshot() map[tokenpool.API]APIMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[tokenpool.API]APIMetrics, len(m.apis))
+	for api, totals := range m.apis {
+		snapshot[api] = *totals
+	}
+	return snapshot
+}
+
+// Measure counts every request in m
+func Measure(m *Metrics) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			m.observe(api, err != nil || resp.StatusCode >= http.StatusBadRequest, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// Record writes every response to dir, Replay answers the same requests from it later.
+// Tokens aren't part of the recording.
+func Record(dir string) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			path, err := recordingPath(dir, req)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+
+			raw, err := httputil.DumpResponse(resp, true)
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("recording response: %w", err)
+			}
+
+			err = os.MkdirAll(dir, 0o755)
+			if err == nil {
+				err = os.WriteFile(path, raw, 0o644)
+			}
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("recording response: %w", err)
+			}
+
+			return resp, nil
+		})
+	}
+}
+
+// Replay answers the requests with the responses Record wrote to dir instead of calling
+// GitHub, so it ends the chain. Retry, Auth and Pace don't belong in front of it, the
+// recorded rate limit headers would make them wait. A request that wasn't recorded fails.
+func Replay(dir string) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			path, err := recordingPath(dir, req)
+			if err != nil {
+				return nil, err
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("replaying %s %s: %w", req.Method, req.URL, err)
+			}
+
+			return http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+		})
+	}
+}
+
+// recordingPath names the recording of req after its method, URL and body
+func recordingPath(dir string, req *http.Request) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL)
+
+	if req.Body != nil {
+		body := req.Body
+		if req.GetBody != nil {
+			var err error
+			body, err = req.GetBody()
+			if err != nil {
+				return "", err
+			}
+		}
+
+		raw, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+		hash.Write(raw)
+
+		if req.GetBody == nil {
+			req.Body = io.NopCloser(bytes.NewReader(raw))
+		}
+	}
+
+	return filepath.Join(dir, hex.EncodeToString(hash.Sum(nil)[:16])+".http"), nil
+}
@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+)
+
+// tag adds name to the X-Chain header, so the test sees the order of the chain
+func tag(name string) Middleware {
+	return func(api tokenpool.API, next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Add("X-Chain", name+":"+string(api))
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestNewClientChainsMiddleware(t *testing.T) {
+	var chain []string
+	var path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chain = r.Header.Values("X-Chain")
+		path = r.URL.Path
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{BaseURL: server.URL + "/"}, tag("outer"), tag("inner"))
+
+	resp, err := client.Get(context.Background(), "/rate_limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if strings.Join(chain, ",") != "outer:rest,inner:rest" || path != "/rate_limit" {
+		t.Fatalf("Expected %v %s to be outer:rest,inner:rest /rate_limit", chain, path)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Write([]byte(`[{"starred_at":"2024-06-01T00:00:00Z"}]`))
+	}))
+
+	metrics := NewMetrics()
+	recording := NewClient(Options{BaseURL: server.URL}, Measure(metrics), Record(dir))
+	times, err := recording.GetStarHistory(context.Background(), "glup3/TrendyGitHub", 1)
+	if err != nil || len(times) != 1 {
+		t.Fatalf("Expected one star, got %v %v", times, err)
+	}
+	server.Close()
+
+	if totals := metrics.Snapshot()[tokenpool.REST]; totals.Requests != 1 || totals.Failures != 0 {
+		t.Fatalf("Expected %+v to count one request", totals)
+	}
+
+	replaying := NewClient(Options{BaseURL: server.URL}, Replay(dir))
+
+	resp, err := replaying.Get(context.Background(), "/repos/glup3/TrendyGitHub/stargazers?page=1&per_page=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !strings.Contains(string(body), "2024-06-01") || resp.Header.Get("X-RateLimit-Remaining") != "42" {
+		t.Fatalf("Expected the recorded response, got %s %v", body, resp.Header)
+	}
+
+	_, err = replaying.Get(context.Background(), "/rate_limit")
+	if err == nil {
+		t.Fatal("Expected a request that wasn't recorded to fail")
+	}
+}
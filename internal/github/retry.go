@@ -0,0 +1,221 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy is the retry budget of a single call
+type RetryPolicy struct {
+	// Attempts counts the first try, 1 disables retries
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy gives up after about half a minute of backoff
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:  5,
+	BaseDelay: time.Second,
+	MaxDelay:  15 * time.Second,
+}
+
+// backoff is the jittered exponential delay before retry n (1-based)
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << (n - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+
+	// the jitter keeps the workers of a job from retrying in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+type BreakerOptions struct {
+	// Threshold is the number of transient failures in a row that opens the breaker
+	Threshold   int
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+}
+
+var DefaultBreakerOptions = BreakerOptions{
+	Threshold:   10,
+	Cooldown:    time.Minute,
+	MaxCooldown: 15 * time.Minute,
+}
+
+// Breaker pauses every request after too many transient failures in a row,
+// so all jobs wait for a degraded GitHub instead of burning their retries.
+// The pause doubles while GitHub keeps failing and resets after a success.
+type Breaker struct {
+	openUntil time.Time
+	now       func() time.Time
+	opts      BreakerOptions
+	failures  int
+	cooldown  time.Duration
+	mu        sync.Mutex
+}
+
+func NewBreaker(opts BreakerOptions) *Breaker {
+	return &Breaker{
+		now:      time.Now,
+		opts:     opts,
+		cooldown: opts.Cooldown,
+	}
+}
+
+// Wait blocks while the breaker is open
+func (b *Breaker) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	delay := b.openUntil.Sub(b.now())
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	return Sleep(ctx, delay)
+}
+
+// Record counts a transient failure or resets the breaker after a success
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.cooldown = b.opts.Cooldown
+		return
+	}
+
+	b.failures++
+	if b.failures < b.opts.Threshold {
+		return
+	}
+
+	b.openUntil = b.now().Add(b.cooldown)
+	b.failures = 0
+
+	log.Warn().
+		Time("until", b.openUntil).
+		Dur("cooldown", b.cooldown).
+		Msg("GitHub is degraded - pausing all requests")
+
+	b.cooldown = min(b.opts.MaxCooldown, b.cooldown*2)
+}
+
+// RetryTransport retries transient failures and secondary rate limits of idempotent
+// requests (GraphQL queries included) with backoff, the breaker is shared by all clients
+func RetryTransport(policy RetryPolicy, breaker *Breaker, wrapped http.RoundTripper) http.RoundTripper {
+	return &retryTransport{policy: policy, breaker: breaker, wrapped: wrapped}
+}
+
+type retryTransport struct {
+	breaker *Breaker
+	wrapped http.RoundTripper
+	policy  RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// a body that can't be replayed is sent once
+	replayable := req.Body == nil || req.GetBody != nil
+
+	for attempt := 1; ; attempt++ {
+		err := t.breaker.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 1 && req.Body != nil {
+			attemptReq, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.wrapped.RoundTrip(attemptReq)
+		retryable, transient := classifyAttempt(ctx, resp, err)
+		t.breaker.Record(transient)
+
+		if !retryable || !replayable || attempt >= t.policy.Attempts {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := t.policy.backoff(attempt)
+		log.Warn().
+			Err(err).
+			Str("url", req.URL.String()).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("retrying GitHub request")
+
+		err = Sleep(ctx, delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// classifyAttempt decides whether to retry and whether the failure counts towards the breaker.
+// Secondary rate limits are retried, the rate limiter paces the next attempt.
+func classifyAttempt(ctx context.Context, resp *http.Response, err error) (retryable bool, transient bool) {
+	if err != nil {
+		transient = ctx.Err() == nil
+		return transient, transient
+	}
+
+	if resp.StatusCode < http.StatusBadRequest {
+		return false, false
+	}
+
+	var ghErr *Error
+	if !errors.As(Peek(resp, time.Now()), &ghErr) {
+		return false, false
+	}
+
+	switch ghErr.Kind {
+	case Transient:
+		return true, true
+	case SecondaryRateLimited:
+		return true, false
+	}
+
+	return false, false
+}
+
+// rewind returns a copy of req with a fresh body for the next attempt
+func rewind(req *http.Request) (*http.Request, error) {
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// Sleep waits for d or until ctx is done, the breaker, the retries and the rate limiter wait with it
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
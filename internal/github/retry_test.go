@@ -0,0 +1,129 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryTransportRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "query" {
+			t.Errorf("Expected %q to equal query", body)
+		}
+
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker(DefaultBreakerOptions)
+	client := http.Client{Transport: RetryTransport(testRetryPolicy, breaker, http.DefaultTransport)}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %d to equal 200", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("Expected %d calls to equal 3", calls.Load())
+	}
+	if breaker.failures != 0 {
+		t.Fatalf("Expected %d failures to be reset after a success", breaker.failures)
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"message":"unicorn"}`))
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: RetryTransport(testRetryPolicy, NewBreaker(DefaultBreakerOptions), http.DefaultTransport)}
+
+	// the last failed response reaches the caller with its body
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Do(&client, req)
+	var ghErr *Error
+	if !errors.As(err, &ghErr) || ghErr.Kind != Transient || ghErr.Message != "unicorn" {
+		t.Fatalf("Expected a transient error with its message, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("Expected %d calls to equal 3", calls.Load())
+	}
+
+	// a deleted repository is not retried
+	calls.Store(0)
+	resp, err := client.Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Fatalf("Expected %d calls to equal 1", calls.Load())
+	}
+}
+
+func TestBreakerPausesAfterThreshold(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	breaker := NewBreaker(BreakerOptions{Threshold: 2, Cooldown: time.Minute, MaxCooldown: 3 * time.Minute})
+	breaker.now = func() time.Time { return now }
+
+	breaker.Record(true)
+	if !breaker.openUntil.IsZero() {
+		t.Fatal("Expected the breaker to stay closed below the threshold")
+	}
+
+	breaker.Record(true)
+	if !breaker.openUntil.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected %s to equal 1m from now", breaker.openUntil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := breaker.Wait(ctx); err == nil {
+		t.Fatal("Expected an open breaker to block until ctx is done")
+	}
+
+	// the cooldown doubles while GitHub keeps failing, capped at the maximum
+	breaker.Record(true)
+	breaker.Record(true)
+	breaker.Record(true)
+	breaker.Record(true)
+	if !breaker.openUntil.Equal(now.Add(3 * time.Minute)) {
+		t.Fatalf("Expected %s to equal 3m from now", breaker.openUntil)
+	}
+
+	breaker.Record(false)
+	if breaker.cooldown != time.Minute {
+		t.Fatalf("Expected %s to be reset to 1m", breaker.cooldown)
+	}
+}
@@ -1,14 +1,17 @@
 package jobs
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/glup3/TrendyGitHub/internal/github"
 	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
 )
 
 // 100 repositories == 1 Unit
@@ -17,34 +20,40 @@ const (
 )
 
 // GitHub REST API limitation: maximum pagination of 400 pages
-func (job *HistoryJob) FetchHistoryUnder40kStars() {
-	const maxAPILimitStarCount = 40_000
-	const maxAPILimitPages = 400
+const MaxRestStarCount = 40_000
+
+// FetchHistoryUnder40kStars stops after limit repositories, 0 fetches until the rate limit is exceeded
+func (job *HistoryJob) FetchHistoryUnder40kStars(ctx context.Context, maxStars int, limit int) error {
+	return job.runner.run(ctx, "history-40k", lockHistory40k, func(ctx context.Context, run *jobRun) error {
+		return job.fetchHistoryUnder40kStars(ctx, maxStars, limit, run)
+	})
+}
+
+func (job *HistoryJob) fetchHistoryUnder40kStars(ctx context.Context, maxStars int, limit int, run *jobRun) error {
 	updatedCount := 0
 
-	for {
-		rateLimit, err := (*job.loader).GetRateLimitRest()
+	for (limit <= 0 || updatedCount < limit) && ctx.Err() == nil {
+		budget, err := job.budget(ctx, tokenpool.REST)
 		if err != nil {
-			log.Error().Err(err).Msg("failed fetching rate limit REST")
-			break
+			return err
 		}
 
-		if rateLimit.Rate.Remaining <= 0 {
-			log.Warn().Int("resetAt", rateLimit.Rate.Reset).Msg("REST API rate limit exceeded")
+		if budget.Remaining <= 0 {
+			log.Warn().Time("resetAt", budget.ResetAt).Msg("REST API rate limit exceeded")
 			break
 		}
 
-		maxStarCount := rateLimit.Rate.Remaining * 400
-		if maxStarCount > maxAPILimitStarCount {
-			maxStarCount = maxAPILimitStarCount
+		maxStarCount := budget.Remaining * 400
+		if maxStarCount > maxStars {
+			maxStarCount = maxStars
 		}
 
-		repo, err := job.repoRepository.FindNextMissing(maxStarCount, repository.OrderAsc)
+		repo, err := job.repoRepository.FindNextMissing(ctx, maxStarCount, repository.OrderAsc)
 		if err != nil {
 			log.Warn().
 				Err(err).
 				Int("maxStarCount", maxStarCount).
-				Int("remainingLimit", rateLimit.Rate.Remaining).
+				Int("remainingLimit", budget.Remaining).
 				Msg("failed fetching next missing repo REST")
 			break
 		}
@@ -53,47 +62,50 @@ func (job *HistoryJob) FetchHistoryUnder40kStars() {
 			Int("id", repo.Id).
 			Str("repository", repo.NameWithOwner).
 			Str("githubId", repo.GithubId).
-			Int("remainingLimit", rateLimit.Rate.Remaining).
+			Int("remainingLimit", budget.Remaining).
 			Msg("fetching history for repo REST")
 
-		err = job.FetchStarHistory(repo)
+		err = job.fetchStarHistory(ctx, repo, run)
 		if err != nil {
-			log.Error().
-				Err(err).
-				Int("id", repo.Id).
-				Str("repository", repo.NameWithOwner).
-				Msg("something happend when fetching REST API star history")
-			break
+			return fmt.Errorf("fetching REST API star history of %s: %w", repo.NameWithOwner, err)
 		}
 
 		updatedCount++
+		run.ReposProcessed++
 	}
 
 	log.Info().Int("count", updatedCount).Msg("REST: done fetching missing star histories")
+	return ctx.Err()
+}
+
+// FetchHistory stops after limit repositories, 0 fetches until the rate limit is exceeded
+func (job *HistoryJob) FetchHistory(ctx context.Context, limit int) error {
+	return job.runner.run(ctx, "history", lockHistory, func(ctx context.Context, run *jobRun) error {
+		return job.fetchHistory(ctx, limit, run)
+	})
 }
 
-func (job *HistoryJob) FetchHistory() {
+func (job *HistoryJob) fetchHistory(ctx context.Context, limit int, run *jobRun) error {
 	updatedCount := 0
 
-	for {
-		rateLimit, err := (*job.loader).GetRateLimit()
+	for (limit <= 0 || updatedCount < limit) && ctx.Err() == nil {
+		budget, err := job.budget(ctx, tokenpool.GraphQL)
 		if err != nil {
-			log.Error().Err(err).Msg("failed fetching rate limit GraphQL")
-			break
+			return err
 		}
 
-		if rateLimit.Remaining <= 0 {
-			log.Warn().Time("resetAt", rateLimit.ResetAt).Msg("GraphQL rate limit exceeded")
+		if budget.Remaining <= 0 {
+			log.Warn().Time("resetAt", budget.ResetAt).Msg("GraphQL rate limit exceeded")
 			break
 		}
 
-		maxStarCount := rateLimit.Remaining * 100
-		repo, err := job.repoRepository.FindNextMissing(maxStarCount, repository.OrderDesc)
+		maxStarCount := budget.Remaining * 100
+		repo, err := job.repoRepository.FindNextMissing(ctx, maxStarCount, repository.OrderDesc)
 		if err != nil {
 			log.Warn().
 				Err(err).
 				Int("maxStarCount", maxStarCount).
-				Int("remainingLimit", rateLimit.Remaining).
+				Int("remainingLimit", budget.Remaining).
 				Msg("failed fetching next missing repo GraphQL")
 			break
 		}
@@ -101,7 +113,7 @@ func (job *HistoryJob) FetchHistory() {
 		log.Info().
 			Int("id", repo.Id).
 			Str("repository", repo.NameWithOwner).
-			Int("remainingLimit", rateLimit.Remaining).
+			Int("remainingLimit", budget.Remaining).
 			Msg("fetching history for repo GraphQL")
 
 		cursor := ""
@@ -109,33 +121,27 @@ func (job *HistoryJob) FetchHistory() {
 		pageCounter := 0
 
 		for {
-			dates, info, err := (*job.loader).LoadRepoStarHistoryDates(repo.GithubId, cursor)
+			dates, info, err := (*job.loader).LoadRepoStarHistoryDates(ctx, repo.GithubId, cursor)
+			run.GraphqlUnits++
 			if err != nil {
-				if strings.Contains(err.Error(), "Could not resolve to a node") ||
-					strings.Contains(err.Error(), "Unavailable For Legal Reasons") {
+				var ghErr *github.Error
+				if errors.As(err, &ghErr) && ghErr.Gone() {
 					log.Warn().
 						Err(err).
 						Str("repository", repo.NameWithOwner).
 						Int("id", repo.Id).
 						Msg("deleting repo because it doesn't exist anymore")
 
-					err = job.deleteDeadRepo(repo)
+					err = job.deleteDeadRepo(ctx, repo)
 					if err != nil {
-						log.Fatal().
-							Err(err).
-							Int("id", repo.Id).
-							Str("repository", repo.NameWithOwner).
-							Msg("failed to delete dead repo")
+						return fmt.Errorf("deleting dead repo %s: %w", repo.NameWithOwner, err)
 					}
 
 					break
-				} else {
-					log.Fatal().
-						Err(err).
-						Int("id", repo.Id).
-						Str("repository", repo.NameWithOwner).
-						Msg("aborting loading star history GraphQL")
 				}
+
+				// the repo stays missing and is fetched from the first page again
+				return fmt.Errorf("loading GraphQL star history of %s: %w", repo.NameWithOwner, err)
 			}
 
 			cursor = info.NextCursor
@@ -156,34 +162,33 @@ func (job *HistoryJob) FetchHistory() {
 			}
 		}
 
-		err = job.aggregateAndInsertHistory(totalDates, repo)
+		err = job.aggregateAndInsertHistory(ctx, totalDates, repo)
 		if err != nil {
-			log.Error().
-				Err(err).
-				Str("githubId", repo.GithubId).
-				Str("repository", repo.NameWithOwner).
-				Msg("something happend when aggregating star history")
-			break
+			return fmt.Errorf("aggregating star history of %s: %w", repo.NameWithOwner, err)
 		}
 
 		updatedCount++
+		run.ReposProcessed++
 	}
 
 	log.Info().Int("count", updatedCount).Msg("GraphQL: done fetching missing star histories")
+	return ctx.Err()
 }
 
-func (job *HistoryJob) FetchStarHistory(repo repository.Repo) error {
+func (job *HistoryJob) fetchStarHistory(ctx context.Context, repo repository.Repo, run *jobRun) error {
 	timestamps := make([]time.Time, 0)
 
-	page1Timestamps, pageInfo, err := (*job.loader).LoadRepoStarHistoryPage(repo.NameWithOwner, 1)
+	page1Timestamps, pageInfo, err := (*job.loader).LoadRepoStarHistoryPage(ctx, repo.NameWithOwner, 1)
+	run.RestUnits++
 	if err != nil {
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "451") {
+		var ghErr *github.Error
+		if errors.As(err, &ghErr) && ghErr.Gone() {
 			log.Warn().
 				Err(err).
 				Str("repository", repo.NameWithOwner).
 				Int("id", repo.Id).
 				Msg("deleting repo because it doesn't exist anymore")
-			return job.deleteDeadRepo(repo)
+			return job.deleteDeadRepo(ctx, repo)
 		}
 
 		log.Error().
@@ -198,7 +203,7 @@ func (job *HistoryJob) FetchStarHistory(repo repository.Repo) error {
 
 	totalPages := pageInfo.LastPage
 	if totalPages == 0 {
-		return job.aggregateAndInsertHistory(timestamps, repo)
+		return job.aggregateAndInsertHistory(ctx, timestamps, repo)
 	}
 
 	var wg sync.WaitGroup
@@ -211,7 +216,7 @@ func (job *HistoryJob) FetchStarHistory(repo repository.Repo) error {
 	worker := func() {
 		defer wg.Done()
 		for page := range pageCh {
-			pageTimestamps, _, err := (*job.loader).LoadRepoStarHistoryPage(repo.NameWithOwner, page)
+			pageTimestamps, _, err := (*job.loader).LoadRepoStarHistoryPage(ctx, repo.NameWithOwner, page)
 			if err != nil {
 				errCh <- err
 				return
@@ -230,6 +235,7 @@ func (job *HistoryJob) FetchStarHistory(repo repository.Repo) error {
 		pageCh <- page
 	}
 	close(pageCh)
+	run.RestUnits += totalPages - 1
 
 	go func() {
 		wg.Wait()
@@ -253,17 +259,17 @@ func (job *HistoryJob) FetchStarHistory(repo repository.Repo) error {
 		return err
 	}
 
-	return job.aggregateAndInsertHistory(timestamps, repo)
+	return job.aggregateAndInsertHistory(ctx, timestamps, repo)
 }
 
-func (job *HistoryJob) aggregateAndInsertHistory(timestamps []time.Time, repo repository.Repo) error {
+func (job *HistoryJob) aggregateAndInsertHistory(ctx context.Context, timestamps []time.Time, repo repository.Repo) error {
 	if len(timestamps) == 0 {
 		log.Warn().
 			Int("id", repo.Id).
 			Str("repository", repo.NameWithOwner).
 			Msg("no timestamps found, will mark as DONE")
 
-		err := job.repoRepository.MarkAsDone(repo.Id)
+		err := job.repoRepository.MarkAsDone(ctx, repo.Id)
 		if err != nil {
 			return fmt.Errorf("unable to mark repo as DONE %w", err)
 		}
@@ -289,7 +295,7 @@ func (job *HistoryJob) aggregateAndInsertHistory(timestamps []time.Time, repo re
 		})
 	}
 
-	err := job.historyRepository.BatchUpsert(inputs)
+	err := job.historyRepository.BatchUpsert(ctx, inputs)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -307,8 +313,8 @@ func (job *HistoryJob) aggregateAndInsertHistory(timestamps []time.Time, repo re
 	return nil
 }
 
-func (job *HistoryJob) deleteDeadRepo(repo repository.Repo) error {
-	err := job.historyRepository.DeleteForRepo(repo.Id)
+func (job *HistoryJob) deleteDeadRepo(ctx context.Context, repo repository.Repo) error {
+	err := job.historyRepository.DeleteForRepo(ctx, repo.Id)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -318,7 +324,7 @@ func (job *HistoryJob) deleteDeadRepo(repo repository.Repo) error {
 		return err
 	}
 
-	err = job.repoRepository.Delete(repo.Id)
+	err = job.repoRepository.Delete(ctx, repo.Id)
 	if err != nil {
 		log.Error().
 			Err(err).
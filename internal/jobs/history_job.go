@@ -11,6 +11,7 @@ import (
 	"github.com/glup3/TrendyGitHub/internal/github"
 	lo "github.com/glup3/TrendyGitHub/internal/loader"
 	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
 	"github.com/rs/zerolog/log"
 )
 
@@ -18,44 +19,87 @@ type HistoryJob struct {
 	loader            *lo.Loader
 	repoRepository    *repository.RepoRepository
 	historyRepository *repository.HistoryRepository
+	trendRepository   *repository.TrendRepository
 	api               *github.GithubClient
+	runner            *jobRunner
 }
 
-func NewHistoryJob(ctx context.Context, db *database.Database, dataLoader *lo.Loader, githubClient *github.GithubClient) *HistoryJob {
+func NewHistoryJob(db *database.Database, dataLoader *lo.Loader, githubClient *github.GithubClient) *HistoryJob {
 	return &HistoryJob{
 		loader:            dataLoader,
-		historyRepository: repository.NewHistoryRepository(ctx, db),
-		repoRepository:    repository.NewRepoRepository(ctx, db),
+		historyRepository: repository.NewHistoryRepository(db),
+		repoRepository:    repository.NewRepoRepository(db),
+		trendRepository:   repository.NewTrendRepository(db),
 		api:               githubClient,
+		runner:            newJobRunner(db),
 	}
 }
 
-func (j *HistoryJob) CreateSnapshot() {
-	log.Info().Msg("creating snapshot")
+// SetLockMode decides whether jobs wait for or skip the same job running in another process
+func (j *HistoryJob) SetLockMode(mode LockMode) {
+	j.runner.lockMode = mode
+}
+
+// budget is the remaining budget of api over all tokens as the responses reported it.
+// GitHub is only asked while a token wasn't used yet or passed its reset.
+func (job *HistoryJob) budget(ctx context.Context, api tokenpool.API) (tokenpool.Budget, error) {
+	budget := job.api.Budget(api)
+	if budget.Known {
+		return budget, nil
+	}
 
-	err := j.historyRepository.CreateSnapshot()
+	rl, err := job.api.GetRateLimit(ctx)
 	if err != nil {
-		log.Fatal().
-			Err(err).
-			Msg("failed creating snapshot")
+		return tokenpool.Budget{}, fmt.Errorf("fetching %s rate limit: %w", api, err)
+	}
+
+	if api == tokenpool.REST {
+		return tokenpool.Budget{Remaining: rl.RemainingRest, ResetAt: time.Unix(int64(rl.ResetRest), 0), Known: true}, nil
 	}
+	return tokenpool.Budget{Remaining: rl.RemainingGraphql, ResetAt: time.Unix(int64(rl.ResetGraphql), 0), Known: true}, nil
+}
+
+func (j *HistoryJob) CreateSnapshot(ctx context.Context) error {
+	return j.runner.run(ctx, "snapshot", "", func(ctx context.Context, run *jobRun) error {
+		log.Info().Msg("creating snapshot")
+
+		err := j.historyRepository.CreateSnapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("creating snapshot: %w", err)
+		}
+
+		log.Info().Msg("finished creating snapshot")
+		return nil
+	})
+}
 
-	log.Info().Msg("finished creating snapshot")
+func (j *HistoryJob) RefreshViews(ctx context.Context) error {
+	return j.runner.run(ctx, "refresh", lockRefresh, j.refreshViews)
 }
 
-func (j *HistoryJob) RefreshViews() {
+func (j *HistoryJob) refreshViews(ctx context.Context, run *jobRun) error {
 	start := time.Now()
-	views := []string{"trend_daily", "trend_weekly", "trend_monthly"}
 
 	log.Info().Msg("refreshing views")
 
-	for _, view := range views {
-
+	for _, period := range repository.TrendPeriods {
+		view := period.View()
 		start := time.Now()
 
-		err := j.historyRepository.RefreshView(view)
+		err := j.historyRepository.RefreshView(ctx, view)
 		if err != nil {
-			log.Fatal().Err(err).Msgf("failed to refresh view %s", view)
+			return fmt.Errorf("refreshing view %s: %w", view, err)
+		}
+
+		err = j.trendRepository.SaveRankings(ctx, period)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to save rankings of view %s", view)
+			run.fail(err)
+		}
+
+		err = j.historyRepository.NotifyViewRefreshed(ctx, view)
+		if err != nil {
+			log.Warn().Err(err).Msgf("failed to notify about refreshed view %s", view)
 		}
 
 		elapsed := time.Since(start)
@@ -63,61 +107,54 @@ func (j *HistoryJob) RefreshViews() {
 	}
 
 	log.Info().Msgf("refreshing views took %s", time.Since(start))
+	return nil
 }
 
-func (job *HistoryJob) Repair40k() {
-	repos, err := job.historyRepository.GetBrokenRepos(40_000)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed fetching repos 40k")
-	}
-
-	for _, repo := range repos {
-		err := job.repair40k(repo)
-		if err != nil {
-			log.Fatal().
-				Err(err).
-				Int("id", repo.Id).
-				Str("repository", repo.NameWithOwner).
-				Msgf("repairing star history 40k failed %s", repo.NameWithOwner)
-		}
-	}
+func (job *HistoryJob) Repair40k(ctx context.Context, maxStarCount int) error {
+	return job.runner.run(ctx, "repair-40k", lockRepair, func(ctx context.Context, run *jobRun) error {
+		return job.repairAll(ctx, maxStarCount, run, job.repair40k)
+	})
+}
 
-	log.Info().Msg("done repairing history 40k")
+func (job *HistoryJob) Repair(ctx context.Context, maxStarCount int) error {
+	return job.runner.run(ctx, "repair", lockRepair, func(ctx context.Context, run *jobRun) error {
+		return job.repairAll(ctx, maxStarCount, run, job.repair)
+	})
 }
 
-func (job *HistoryJob) Repair() {
-	repos, err := job.historyRepository.GetBrokenRepos(1_000_000)
+// repairAll stops at the first failing repo, repaired repos are removed from history_repairs
+func (job *HistoryJob) repairAll(ctx context.Context, maxStarCount int, run *jobRun, repair func(context.Context, repository.BrokenRepo, *jobRun) error) error {
+	repos, err := job.historyRepository.GetBrokenRepos(ctx, maxStarCount)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed fetching repos")
+		return fmt.Errorf("fetching broken repos: %w", err)
 	}
 
 	for _, repo := range repos {
-		err := job.repair(repo)
+		err := repair(ctx, repo, run)
 		if err != nil {
-			log.Fatal().
-				Err(err).
-				Int("id", repo.Id).
-				Str("repository", repo.NameWithOwner).
-				Msgf("repairing star history failed %s", repo.NameWithOwner)
+			return fmt.Errorf("repairing star history of %s: %w", repo.NameWithOwner, err)
 		}
+
+		run.ReposProcessed++
 	}
 
-	log.Info().Msg("done repairing history")
+	log.Info().Int("count", run.ReposProcessed).Msg("done repairing history")
+	return nil
 }
 
-func (job *HistoryJob) repair40k(repo repository.BrokenRepo) error {
-	rl, err := job.api.GetRateLimit()
+func (job *HistoryJob) repair40k(ctx context.Context, repo repository.BrokenRepo, run *jobRun) error {
+	budget, err := job.budget(ctx, tokenpool.REST)
 	if err != nil {
 		return err
 	}
-	if rl.RemainingRest <= 0 {
-		return fmt.Errorf("next REST rate limit reset at %d", rl.ResetRest)
+	if budget.Remaining <= 0 {
+		return fmt.Errorf("next REST rate limit reset at %s", budget.ResetAt)
 	}
 
 	log.Info().
 		Int("id", repo.Id).
 		Str("repository", repo.NameWithOwner).
-		Int("remaining", rl.RemainingRest).
+		Int("remaining", budget.Remaining).
 		Msgf("repairing history 40k for repo %s", repo.NameWithOwner)
 
 	var totalTimes []time.Time
@@ -125,7 +162,8 @@ func (job *HistoryJob) repair40k(repo repository.BrokenRepo) error {
 
 Pages:
 	for page := lastPage; page >= 1; page-- {
-		times, err := job.api.GetStarHistory(repo.NameWithOwner, page)
+		times, err := job.api.GetStarHistory(ctx, repo.NameWithOwner, page)
+		run.RestUnits++
 		if err != nil {
 			return err
 		}
@@ -144,13 +182,13 @@ Pages:
 	}
 
 	if len(totalTimes) > 0 {
-		err := job.updateAccumulatedStars(repo, totalTimes)
+		err := job.updateAccumulatedStars(ctx, repo, totalTimes)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = job.historyRepository.RemoveBrokenRepo(repo.Id)
+	err = job.historyRepository.RemoveBrokenRepo(ctx, repo.Id)
 	if err != nil {
 		return err
 	}
@@ -163,19 +201,19 @@ Pages:
 	return nil
 }
 
-func (job *HistoryJob) repair(repo repository.BrokenRepo) error {
-	rl, err := job.api.GetRateLimit()
+func (job *HistoryJob) repair(ctx context.Context, repo repository.BrokenRepo, run *jobRun) error {
+	budget, err := job.budget(ctx, tokenpool.GraphQL)
 	if err != nil {
 		return err
 	}
-	if rl.RemainingGraphql <= 0 {
-		return fmt.Errorf("next Graphql rate limit reset at %d", rl.ResetGraphql)
+	if budget.Remaining <= 0 {
+		return fmt.Errorf("next Graphql rate limit reset at %s", budget.ResetAt)
 	}
 
 	log.Info().
 		Int("id", repo.Id).
 		Str("repository", repo.NameWithOwner).
-		Int("remaining", rl.RemainingGraphql).
+		Int("remaining", budget.Remaining).
 		Msgf("repairing history for repo %s", repo.NameWithOwner)
 
 	var totalTimes []time.Time
@@ -183,7 +221,8 @@ func (job *HistoryJob) repair(repo repository.BrokenRepo) error {
 
 Cursors:
 	for cursor != "END" {
-		times, nextCursor, err := job.api.GetStarHistoryV2(repo.GithubId, cursor)
+		times, nextCursor, err := job.api.GetStarHistoryV2(ctx, repo.GithubId, cursor)
+		run.GraphqlUnits++
 		if err != nil {
 			return err
 		}
@@ -200,13 +239,13 @@ Cursors:
 	}
 
 	if len(totalTimes) > 0 {
-		err := job.updateAccumulatedStars(repo, totalTimes)
+		err := job.updateAccumulatedStars(ctx, repo, totalTimes)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = job.historyRepository.RemoveBrokenRepo(repo.Id)
+	err = job.historyRepository.RemoveBrokenRepo(ctx, repo.Id)
 	if err != nil {
 		return err
 	}
@@ -219,12 +258,12 @@ Cursors:
 	return nil
 }
 
-func (job *HistoryJob) updateAccumulatedStars(repo repository.BrokenRepo, times []time.Time) error {
+func (job *HistoryJob) updateAccumulatedStars(ctx context.Context, repo repository.BrokenRepo, times []time.Time) error {
 	if len(times) == 0 {
 		return nil
 	}
 
-	baseStarCount, err := job.repoRepository.GetStarCount(repo.Id, repo.UntilDate.Add(-24*time.Hour))
+	baseStarCount, err := job.repoRepository.GetStarCount(ctx, repo.Id, repo.UntilDate.Add(-24*time.Hour))
 	if err != nil {
 		return err
 	}
@@ -240,7 +279,7 @@ func (job *HistoryJob) updateAccumulatedStars(repo repository.BrokenRepo, times
 		})
 	}
 
-	err = job.historyRepository.BatchUpsert(inputs)
+	err = job.historyRepository.BatchUpsert(ctx, inputs)
 	if err != nil {
 		return err
 	}
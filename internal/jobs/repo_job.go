@@ -2,11 +2,13 @@ package jobs
 
 import (
 	"context"
-	"strings"
-	"time"
+	"errors"
+	"fmt"
+	"strconv"
 
 	config "github.com/glup3/TrendyGitHub/internal"
 	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/github"
 	lo "github.com/glup3/TrendyGitHub/internal/loader"
 	"github.com/glup3/TrendyGitHub/internal/repository"
 	"github.com/rs/zerolog/log"
@@ -26,28 +28,45 @@ var pagination_100_based_cursors = [...]string{
 }
 
 type RepoJob struct {
-	loader             *lo.Loader
-	repoRepository     *repository.RepoRepository
-	settingsRepository *repository.SettingsRepository
+	loader              *lo.Loader
+	repoRepository      *repository.RepoRepository
+	settingsRepository  *repository.SettingsRepository
+	watchlistRepository *repository.WatchlistRepository
+	runner              *jobRunner
 }
 
-func NewRepoJob(ctx context.Context, db *database.Database, dataLoader *lo.Loader) *RepoJob {
+func NewRepoJob(db *database.Database, dataLoader *lo.Loader) *RepoJob {
 	return &RepoJob{
-		loader:             dataLoader,
-		repoRepository:     repository.NewRepoRepository(ctx, db),
-		settingsRepository: repository.NewSettingsRepository(ctx, db),
+		loader:              dataLoader,
+		repoRepository:      repository.NewRepoRepository(db),
+		settingsRepository:  repository.NewSettingsRepository(db),
+		watchlistRepository: repository.NewWatchlistRepository(db),
+		runner:              newJobRunner(db),
 	}
 }
 
-func (job *RepoJob) Search() {
-	unitCount := 0
+// SetLockMode decides whether Search waits for or skips a search running in another process
+func (job *RepoJob) SetLockMode(mode LockMode) {
+	job.runner.lockMode = mode
+}
+
+// Search is paced by the rate limiter of the loader, a secondary rate limit retries the page.
+// The cursor is saved after every page, a cancelled search continues from there.
+func (job *RepoJob) Search(ctx context.Context) error {
+	return job.runner.run(ctx, "search", lockSearch, func(ctx context.Context, run *jobRun) error {
+		return job.search(ctx, run)
+	})
+}
 
+func (job *RepoJob) search(ctx context.Context, run *jobRun) error {
 	for {
-		settings, err := job.settingsRepository.Load()
+		settings, err := job.settingsRepository.Load(ctx)
 		if err != nil {
-			log.Fatal().Err(err).Msgf("failed loading settings")
+			return fmt.Errorf("loading settings: %w", err)
 		}
 
+		run.Cursor = strconv.Itoa(settings.CurrentMaxStarCount)
+
 		if !settings.IsEnabled {
 			log.Info().Msg("repository crawling is disabled")
 			break
@@ -58,41 +77,38 @@ func (job *RepoJob) Search() {
 			break
 		}
 
-		if unitCount >= settings.TimeoutMaxUnits {
-			log.Info().Msgf("rate limit prevention - waiting %d seconds", settings.TimeoutSecondsPrevent)
-			time.Sleep(time.Duration(settings.TimeoutSecondsPrevent) * time.Second)
-			unitCount = 0
-		}
-
 		log.Info().Msgf("started fetching stars >= %d", settings.CurrentMaxStarCount)
 
 		rateLimited := false
-		repos, pageInfo, err := (*job.loader).LoadMultipleRepos(settings.CurrentMaxStarCount, pagination_100_based_cursors[:])
-		if err != nil {
-			if strings.Contains(err.Error(), "secondary") {
-				rateLimited = true
-			} else {
-				log.Error().Err(err).Msg("something went wrong during loading")
-			}
+		repos, pageInfo, err := (*job.loader).LoadMultipleRepos(ctx, settings.CurrentMaxStarCount, pagination_100_based_cursors[:])
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-
-		unitCount += pageInfo.UnitCosts
-
-		inputs := config.MapGitHubReposToInputs(repos)
-		err = job.repoRepository.UpsertMany(inputs)
-		if err != nil {
-			log.Error().Err(err).Msg("upserting failed - aborting")
+		var ghErr *github.Error
+		switch {
+		case err == nil:
+		case errors.As(err, &ghErr) && ghErr.Kind == github.PrimaryRateLimited:
+			// the cursor stays, the next run continues after the reset
+			log.Warn().Time("resetAt", ghErr.ResetAt).Msg("GraphQL rate limit exceeded")
+			return nil
+		case errors.As(err, &ghErr) && ghErr.Kind == github.SecondaryRateLimited:
+			rateLimited = true
+		default:
+			log.Error().Err(err).Msg("something went wrong during loading")
+			run.fail(err)
 		}
 
-		err = job.repoRepository.UpsertLanguages(mapUniqueLanguages(repos))
+		run.GraphqlUnits += pageInfo.UnitCosts
+		run.ReposProcessed += len(repos)
+
+		err = job.upsertRepos(ctx, repos)
 		if err != nil {
-			log.Warn().Err(err).Msg("ignore upsert language errors")
+			return err
 		}
 
+		// the limiter holds back the retry until GitHub lets us in again
 		if rateLimited {
-			log.Info().Msgf("got rate limited - waiting %d seconds", settings.TimeoutSecondsExceeded)
-			time.Sleep(time.Duration(settings.TimeoutSecondsExceeded) * time.Second)
-			unitCount = 0
+			log.Info().Msg("got rate limited - retrying page")
 			continue
 		}
 
@@ -101,21 +117,27 @@ func (job *RepoJob) Search() {
 			break
 		}
 
-		err = job.settingsRepository.UpdateStarCountCursor(pageInfo.NextMaxStarCount, settings.ID)
+		err = job.settingsRepository.UpdateStarCountCursor(ctx, pageInfo.NextMaxStarCount, settings.ID)
 		if err != nil {
-			log.Fatal().Err(err).Msg("updating max star count failed")
+			return fmt.Errorf("updating max star count: %w", err)
 		}
 	}
 
 	log.Info().Msg("done fetching repositories")
+	return nil
 }
 
-func (job *RepoJob) ResetStarCountCursor(settingsID int) {
-	err := job.settingsRepository.ResetStarCountCursor(settingsID)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed resetting star count cursor")
-	}
-	log.Info().Msg("finished resetting star count cursor")
+// ResetStarCountCursor shares the lock of the search, a running search would overwrite the cursor
+func (job *RepoJob) ResetStarCountCursor(ctx context.Context, settingsID int) error {
+	return job.runner.run(ctx, "reset", lockSearch, func(ctx context.Context, run *jobRun) error {
+		err := job.settingsRepository.ResetStarCountCursor(ctx, settingsID)
+		if err != nil {
+			return fmt.Errorf("resetting star count cursor: %w", err)
+		}
+
+		log.Info().Msg("finished resetting star count cursor")
+		return nil
+	})
 }
 
 func mapUniqueLanguages(repos []lo.GitHubRepo) []repository.LanguageInput {
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// LockMode decides what a job does when another process already runs it
+type LockMode int
+
+const (
+	LockSkip LockMode = iota
+	LockWait
+	// LockFail skips like LockSkip but returns ErrSkipped, one-off runs must tell that nothing ran
+	LockFail
+)
+
+// ErrSkipped is returned in LockFail mode when another process holds the lock of the job
+var ErrSkipped = errors.New("skipped because another process is running the job")
+
+const (
+	lockSearch     = "tgh:search"
+	lockHistory    = "tgh:history"
+	lockHistory40k = "tgh:history-40k"
+	// repair and repair-40k pick from the same history_repairs rows
+	lockRepair  = "tgh:repair"
+	lockRefresh = "tgh:refresh"
+)
+
+// jobRun collects what a single job invocation spent and processed
+type jobRun struct {
+	repository.RunResult
+}
+
+// fail records an error the job recovered from
+func (r *jobRun) fail(err error) {
+	r.Error = err.Error()
+}
+
+type jobRunner struct {
+	db            *database.Database
+	runRepository *repository.RunRepository
+	lockMode      LockMode
+}
+
+func newJobRunner(db *database.Database) *jobRunner {
+	return &jobRunner{
+		db:            db,
+		runRepository: repository.NewRunRepository(db),
+	}
+}
+
+// run records fn as a job run of mode, fn only runs while holding the advisory lock
+// unless lock is empty
+func (r *jobRunner) run(ctx context.Context, mode string, lock string, fn func(ctx context.Context, run *jobRun) error) error {
+	// the ledger and the lock must be updated even if the job got cancelled
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	if lock != "" {
+		unlock, err := r.lock(ctx, lock)
+		if errors.Is(err, database.ErrLockHeld) {
+			log.Info().Str("lock", lock).Msg("skipping job because another process is running it")
+			r.record(cleanupCtx, mode, repository.RunResult{Status: repository.RunSkipped})
+			if r.lockMode == LockFail {
+				return ErrSkipped
+			}
+			return nil
+		}
+		if err != nil {
+			r.record(cleanupCtx, mode, repository.RunResult{Status: repository.RunFailed, Error: err.Error()})
+			return err
+		}
+		defer unlock(cleanupCtx)
+	}
+
+	id, err := r.runRepository.Start(ctx, mode)
+	if err != nil {
+		log.Warn().Err(err).Str("mode", mode).Msg("failed to record job start")
+	}
+
+	run := jobRun{}
+	runErr := fn(ctx, &run)
+
+	run.Status = repository.RunSucceeded
+	switch {
+	case runErr != nil && ctx.Err() != nil:
+		run.Status = repository.RunCancelled
+		run.fail(runErr)
+	case runErr != nil:
+		run.Status = repository.RunFailed
+		run.fail(runErr)
+	case run.Error != "":
+		run.Status = repository.RunFailed
+	}
+
+	if id != 0 {
+		err = r.runRepository.Finish(cleanupCtx, id, run.RunResult)
+		if err != nil {
+			log.Warn().Err(err).Str("mode", mode).Msg("failed to record job end")
+		}
+	}
+
+	return runErr
+}
+
+// record stores a run that never started
+func (r *jobRunner) record(ctx context.Context, mode string, result repository.RunResult) {
+	id, err := r.runRepository.Start(ctx, mode)
+	if err == nil {
+		err = r.runRepository.Finish(ctx, id, result)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("mode", mode).Msg("failed to record job run")
+	}
+}
+
+func (r *jobRunner) lock(ctx context.Context, name string) (func(ctx context.Context), error) {
+	var lock *database.Lock
+	var err error
+
+	if r.lockMode == LockWait {
+		log.Info().Str("lock", name).Msg("waiting for lock")
+		lock, err = r.db.Lock(ctx, name)
+	} else {
+		lock, err = r.db.TryLock(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) {
+		err := lock.Unlock(ctx)
+		if err != nil {
+			log.Warn().Err(err).Str("lock", name).Msg("failed to unlock")
+		}
+	}, nil
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	config "github.com/glup3/TrendyGitHub/internal"
+	lo "github.com/glup3/TrendyGitHub/internal/loader"
+	"github.com/rs/zerolog/log"
+)
+
+// Track resolves a repository through GraphQL and adds it to a watchlist. Tracked repositories
+// are snapshotted and get their history fetched like the ones found by the search.
+func (job *RepoJob) Track(ctx context.Context, watchlist string, nameWithOwner string) (lo.GitHubRepo, error) {
+	repo, err := (*job.loader).LoadRepo(ctx, nameWithOwner)
+	if err != nil {
+		return repo, fmt.Errorf("resolving %s: %w", nameWithOwner, err)
+	}
+
+	err = job.upsertRepos(ctx, []lo.GitHubRepo{repo})
+	if err != nil {
+		return repo, err
+	}
+
+	err = job.watchlistRepository.Track(ctx, watchlist, repo.Id)
+	if err != nil {
+		return repo, fmt.Errorf("tracking %s: %w", repo.NameWithOwner, err)
+	}
+
+	log.Info().
+		Str("repository", repo.NameWithOwner).
+		Str("watchlist", watchlist).
+		Int("stars", repo.StarCount).
+		Msg("tracking repository")
+
+	return repo, nil
+}
+
+// RefreshTracked updates the star counts of all tracked repositories, the search
+// doesn't see the ones below its minimum star count
+func (job *RepoJob) RefreshTracked(ctx context.Context) error {
+	return job.runner.run(ctx, "refresh-tracked", "", func(ctx context.Context, run *jobRun) error {
+		return job.refreshTracked(ctx, run)
+	})
+}
+
+func (job *RepoJob) refreshTracked(ctx context.Context, run *jobRun) error {
+	tracked, err := job.watchlistRepository.GetTrackedRepos(ctx)
+	if err != nil {
+		return fmt.Errorf("loading tracked repositories: %w", err)
+	}
+
+	repos := []lo.GitHubRepo{}
+	for _, trackedRepo := range tracked {
+		repo, err := (*job.loader).LoadRepo(ctx, trackedRepo.NameWithOwner)
+		run.GraphqlUnits++
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// deleted or renamed repositories keep their last star count
+			log.Warn().
+				Err(err).
+				Int("id", trackedRepo.Id).
+				Str("repository", trackedRepo.NameWithOwner).
+				Msg("failed refreshing tracked repository")
+			run.fail(err)
+			continue
+		}
+
+		repos = append(repos, repo)
+	}
+
+	if len(repos) == 0 {
+		return nil
+	}
+
+	err = job.upsertRepos(ctx, repos)
+	if err != nil {
+		return err
+	}
+
+	run.ReposProcessed += len(repos)
+	log.Info().Int("count", len(repos)).Msg("refreshed tracked repositories")
+
+	return nil
+}
+
+func (job *RepoJob) upsertRepos(ctx context.Context, repos []lo.GitHubRepo) error {
+	err := job.repoRepository.UpsertMany(ctx, config.MapGitHubReposToInputs(repos))
+	if err != nil {
+		return fmt.Errorf("upserting repositories: %w", err)
+	}
+
+	err = job.repoRepository.UpsertLanguages(ctx, mapUniqueLanguages(repos))
+	if err != nil {
+		log.Warn().Err(err).Msg("ignore upsert language errors")
+	}
+
+	return nil
+}
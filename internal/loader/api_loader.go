@@ -3,8 +3,8 @@ package loader
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -12,6 +12,9 @@ import (
 	"time"
 
 	"github.com/glup3/TrendyGitHub/generated"
+	"github.com/glup3/TrendyGitHub/internal/github"
+	"github.com/glup3/TrendyGitHub/internal/ratelimit"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
 )
 
 const (
@@ -20,22 +23,28 @@ const (
 )
 
 type APILoader struct {
-	ctx    context.Context
-	apiKey string
+	client  *github.GithubClient
+	limiter *ratelimit.Limiter
 }
 
-func NewAPILoader(ctx context.Context, apiKey string) *APILoader {
-	return &APILoader{ctx: ctx, apiKey: apiKey}
+// NewAPILoader loads through client, limiter is the one of its chain
+func NewAPILoader(client *github.GithubClient, limiter *ratelimit.Limiter) *APILoader {
+	return &APILoader{client: client, limiter: limiter}
 }
 
-func (l *APILoader) LoadRepos(maxStarCount int, cursor string) ([]GitHubRepo, *PageInfo, error) {
-	client := GetApiClient(l.apiKey)
+func (l *APILoader) LoadRepos(ctx context.Context, maxStarCount int, cursor string) ([]GitHubRepo, *PageInfo, error) {
+	client := l.client.GraphQL()
 
-	resp, err := generated.GetPublicRepos(l.ctx, client, fmt.Sprintf("is:public stars:%d..%d", minStarCount, maxStarCount), perPage, cursor)
+	// the token the page is sent with pays for its cost
+	ctx = ratelimit.Charged(ctx)
+	resp, err := generated.GetPublicRepos(ctx, client, fmt.Sprintf("is:public stars:%d..%d", minStarCount, maxStarCount), perPage, cursor)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// a search page costs more than the single point the transport reserved
+	l.limiter.Spend(ctx, tokenpool.GraphQL, resp.RateLimit.Cost)
+
 	repos := make([]GitHubRepo, len(resp.Search.Edges))
 	for i, edge := range resp.Search.Edges {
 		repo, _ := edge.Node.(*generated.GetPublicReposSearchSearchResultItemConnectionEdgesSearchResultItemEdgeNodeRepository)
@@ -78,7 +87,7 @@ func mapLanguages(edges []generated.GetPublicReposSearchSearchResultItemConnecti
 	return languages
 }
 
-func (l *APILoader) LoadMultipleRepos(maxStarCount int, cursors []string) ([]GitHubRepo, *PageInfo, error) {
+func (l *APILoader) LoadMultipleRepos(ctx context.Context, maxStarCount int, cursors []string) ([]GitHubRepo, *PageInfo, error) {
 	var wg sync.WaitGroup
 	repoChan := make(chan []GitHubRepo, len(cursors))
 	pageInfoChan := make(chan *PageInfo, len(cursors))
@@ -86,7 +95,7 @@ func (l *APILoader) LoadMultipleRepos(maxStarCount int, cursors []string) ([]Git
 
 	loadReposWorker := func(cursor string) {
 		defer wg.Done()
-		repos, pageInfo, err := l.LoadRepos(maxStarCount, cursor)
+		repos, pageInfo, err := l.LoadRepos(ctx, maxStarCount, cursor)
 		if err != nil {
 			errChan <- err
 			return
@@ -132,16 +141,50 @@ func (l *APILoader) LoadMultipleRepos(maxStarCount int, cursors []string) ([]Git
 	}
 
 	if len(allErrors) > 0 {
-		return allRepos, pageInfo, fmt.Errorf("some fetches failed: %v", allErrors)
+		return allRepos, pageInfo, fmt.Errorf("some fetches failed: %w", errors.Join(allErrors...))
 	}
 
 	return allRepos, pageInfo, nil
 }
 
-func (l *APILoader) LoadRepoStarHistoryDates(githubId string, cursor string) ([]time.Time, *StarPageInfo, error) {
-	client := GetApiClient(l.apiKey)
+// LoadRepo resolves a single repository by name, also outside of the search window
+func (l *APILoader) LoadRepo(ctx context.Context, nameWithOwner string) (GitHubRepo, error) {
+	owner, name, ok := strings.Cut(nameWithOwner, "/")
+	if !ok || owner == "" || name == "" {
+		return GitHubRepo{}, fmt.Errorf("invalid repository name %s, expected owner/name", nameWithOwner)
+	}
+
+	client := l.client.GraphQL()
 
-	resp, err := generated.GetStarGazers(l.ctx, client, githubId, cursor)
+	resp, err := generated.GetRepository(ctx, client, owner, name)
+	if err != nil {
+		return GitHubRepo{}, err
+	}
+
+	repo := resp.Repository
+	languages := []Language{}
+	for _, edge := range repo.Languages.Edges {
+		if len(edge.Node.Name) > 0 {
+			languages = append(languages, Language{edge.Node.Name, edge.Node.Color})
+		}
+	}
+
+	return GitHubRepo{
+		Id:              repo.Id,
+		Name:            repo.Name,
+		NameWithOwner:   repo.NameWithOwner,
+		StarCount:       repo.StargazerCount,
+		ForkCount:       repo.ForkCount,
+		PrimaryLanguage: defaultLanguage(repo.PrimaryLanguage.Name),
+		Description:     repo.Description,
+		Languages:       languages,
+	}, nil
+}
+
+func (l *APILoader) LoadRepoStarHistoryDates(ctx context.Context, githubId string, cursor string) ([]time.Time, *StarPageInfo, error) {
+	client := l.client.GraphQL()
+
+	resp, err := generated.GetStarGazers(ctx, client, githubId, cursor)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -165,27 +208,16 @@ func (l *APILoader) LoadRepoStarHistoryDates(githubId string, cursor string) ([]
 }
 
 // page is 1-based
-func (l *APILoader) LoadRepoStarHistoryPage(repoNameWithOwner string, page int) ([]time.Time, *StarHistoryHeader, error) {
-	client := GetRestApiClient(l.apiKey)
-
+func (l *APILoader) LoadRepoStarHistoryPage(ctx context.Context, repoNameWithOwner string, page int) ([]time.Time, *StarHistoryHeader, error) {
 	var dateTimes []time.Time
 	var pageInfo StarHistoryHeader
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/stargazers?page=%d&per_page=100", repoNameWithOwner, page), nil)
+	resp, err := l.client.Get(ctx, fmt.Sprintf("/repos/%s/stargazers?page=%d&per_page=100", repoNameWithOwner, page))
 	if err != nil {
-		return dateTimes, nil, err
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return dateTimes, nil, err
+		return dateTimes, nil, fmt.Errorf("failed to fetch stargazers: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return dateTimes, nil, fmt.Errorf("failed to fetch stargazers: %s", resp.Status)
-	}
-
 	var rawStargazers []map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&rawStargazers); err != nil {
 		return dateTimes, nil, err
@@ -244,21 +276,30 @@ func extractPageFromURL(u *url.URL) int {
 	return page
 }
 
-func (l *APILoader) GetRateLimit() (*RateLimit, error) {
-	client := GetApiClient(l.apiKey)
+// GetRateLimit sums up the GraphQL budgets of all tokens, ResetAt is the earliest reset
+func (l *APILoader) GetRateLimit(ctx context.Context) (*RateLimit, error) {
+	client := l.client.GraphQL()
+	rateLimit := &RateLimit{}
 
-	resp, err := generated.GetRateLimit(l.ctx, client)
+	err := l.client.ForEachToken(ctx, func(ctx context.Context) error {
+		resp, err := generated.GetRateLimit(github.Unmetered(ctx), client)
+		if err != nil {
+			return err
+		}
+
+		rateLimit.Remaining += resp.RateLimit.Remaining
+		rateLimit.Used += resp.RateLimit.Used
+		rateLimit.Limit += resp.RateLimit.Limit
+		if rateLimit.ResetAt.IsZero() || resp.RateLimit.ResetAt.Before(rateLimit.ResetAt) {
+			rateLimit.ResetAt = resp.RateLimit.ResetAt
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	rateLimit := &RateLimit{
-		Remaining: resp.RateLimit.Remaining,
-		Used:      resp.RateLimit.Used,
-		Limit:     resp.RateLimit.Limit,
-		ResetAt:   resp.RateLimit.ResetAt,
-	}
-
 	return rateLimit, nil
 }
 
@@ -270,32 +311,34 @@ type RateLimitRest struct {
 	} `json:"rate"`
 }
 
-func (l *APILoader) GetRateLimitRest() (*RateLimitRest, error) {
-	client := GetRestApiClient(l.apiKey)
-	if client == nil {
-		return nil, fmt.Errorf("failed to get HTTP client")
-	}
+// GetRateLimitRest sums up the REST budgets of all tokens, Reset is the earliest reset
+func (l *APILoader) GetRateLimitRest(ctx context.Context) (*RateLimitRest, error) {
+	rateLimit := &RateLimitRest{}
 
-	req, err := http.NewRequest("GET", "https://api.github.com/rate_limit", nil)
-	if err != nil {
-		return nil, err
-	}
+	err := l.client.ForEachToken(ctx, func(ctx context.Context) error {
+		resp, err := l.client.Get(github.Unmetered(ctx), "/rate_limit")
+		if err != nil {
+			return fmt.Errorf("failed to get rate limit: %w", err)
+		}
+		defer resp.Body.Close()
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		var tokenLimit RateLimitRest
+		err = json.NewDecoder(resp.Body).Decode(&tokenLimit)
+		if err != nil {
+			return err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get rate limit: %s", resp.Status)
-	}
+		rateLimit.Rate.Limit += tokenLimit.Rate.Limit
+		rateLimit.Rate.Remaining += tokenLimit.Rate.Remaining
+		if rateLimit.Rate.Reset == 0 || tokenLimit.Rate.Reset < rateLimit.Rate.Reset {
+			rateLimit.Rate.Reset = tokenLimit.Rate.Reset
+		}
 
-	var rateLimit RateLimitRest
-	err = json.NewDecoder(resp.Body).Decode(&rateLimit)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &rateLimit, nil
+	return rateLimit, nil
 }
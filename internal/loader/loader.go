@@ -1,6 +1,9 @@
 package loader
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Language struct {
 	Name  string
@@ -45,10 +48,11 @@ type StarHistoryHeader struct {
 }
 
 type Loader interface {
-	LoadRepos(maxStarCount int, cursor string) ([]GitHubRepo, *PageInfo, error)
-	LoadMultipleRepos(maxStarCount int, cursors []string) ([]GitHubRepo, *PageInfo, error)
-	LoadRepoStarHistoryDates(githubId string, cursor string) ([]time.Time, *StarPageInfo, error)
-	LoadRepoStarHistoryPage(repoNameWithOwner string, page int) ([]time.Time, *StarHistoryHeader, error)
-	GetRateLimit() (*RateLimit, error)
-	GetRateLimitRest() (*RateLimitRest, error)
+	LoadRepos(ctx context.Context, maxStarCount int, cursor string) ([]GitHubRepo, *PageInfo, error)
+	LoadMultipleRepos(ctx context.Context, maxStarCount int, cursors []string) ([]GitHubRepo, *PageInfo, error)
+	LoadRepo(ctx context.Context, nameWithOwner string) (GitHubRepo, error)
+	LoadRepoStarHistoryDates(ctx context.Context, githubId string, cursor string) ([]time.Time, *StarPageInfo, error)
+	LoadRepoStarHistoryPage(ctx context.Context, repoNameWithOwner string, page int) ([]time.Time, *StarHistoryHeader, error)
+	GetRateLimit(ctx context.Context) (*RateLimit, error)
+	GetRateLimitRest(ctx context.Context) (*RateLimitRest, error)
 }
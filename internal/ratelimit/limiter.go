@@ -0,0 +1,256 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/github"
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+	"github.com/rs/zerolog/log"
+)
+
+// lowBudget is the share of the primary limit below which the remaining points
+// get spread evenly until the reset
+const lowBudget = 0.1
+
+// Options are the request spacings per API, unknown APIs use the REST ones
+type Options struct {
+	// MinInterval is the spacing the limiter returns to while requests succeed
+	MinInterval map[tokenpool.API]time.Duration
+	// MaxInterval caps the spacing after repeated secondary rate limits
+	MaxInterval time.Duration
+}
+
+// DefaultOptions stay below the documented secondary limits of 900 REST and
+// 2000 GraphQL points per minute
+var DefaultOptions = Options{
+	MinInterval: map[tokenpool.API]time.Duration{
+		tokenpool.REST:    70 * time.Millisecond,
+		tokenpool.GraphQL: 30 * time.Millisecond,
+	},
+	MaxInterval: 5 * time.Second,
+}
+
+// Limiter paces the requests of all transports by the rate limit headers of GitHub.
+// Every token is paced on its own, only a secondary limit blocks the whole API.
+// Secondary limits widen the spacing of a token, successful responses narrow it again.
+type Limiter struct {
+	apis  map[tokenpool.API]*apiState
+	slots map[slotKey]*slot
+	opts  Options
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+type apiState struct {
+	blockedUntil time.Time
+}
+
+// slotKey identifies a token on an API
+type slotKey struct {
+	api           tokenpool.API
+	authorization string
+}
+
+// slot paces the requests of a token on an API by its primary limit
+type slot struct {
+	next     time.Time
+	budget   budget
+	interval time.Duration
+}
+
+type budget struct {
+	resetAt   time.Time
+	limit     int
+	remaining int
+	known     bool
+}
+
+func New(opts Options) *Limiter {
+	return &Limiter{
+		apis:  make(map[tokenpool.API]*apiState),
+		slots: make(map[slotKey]*slot),
+		opts:  opts,
+		now:   time.Now,
+	}
+}
+
+func (l *Limiter) minInterval(api tokenpool.API) time.Duration {
+	if interval, ok := l.opts.MinInterval[api]; ok {
+		return interval
+	}
+	return l.opts.MinInterval[tokenpool.REST]
+}
+
+func (l *Limiter) state(api tokenpool.API) *apiState {
+	s, ok := l.apis[api]
+	if !ok {
+		s = &apiState{}
+		l.apis[api] = s
+	}
+	return s
+}
+
+func (l *Limiter) slot(api tokenpool.API, authorization string) *slot {
+	key := slotKey{api, authorization}
+	s, ok := l.slots[key]
+	if !ok {
+		s = &slot{interval: l.minInterval(api)}
+		l.slots[key] = s
+	}
+	return s
+}
+
+// Wait blocks until the next request of authorization against api may be sent
+func (l *Limiter) Wait(ctx context.Context, api tokenpool.API, authorization string) error {
+	delay := l.reserve(api, authorization, 1)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	return github.Sleep(ctx, delay)
+}
+
+type chargeKey struct{}
+
+// charge is the token the last request of a context was sent with
+type charge struct {
+	authorization string
+	mu            sync.Mutex
+}
+
+// Charged remembers the token the requests of ctx are sent with, so Spend can book
+// their cost on it
+func Charged(ctx context.Context) context.Context {
+	return context.WithValue(ctx, chargeKey{}, &charge{})
+}
+
+// Spend accounts for a GraphQL query of a Charged ctx that cost more than a single point
+func (l *Limiter) Spend(ctx context.Context, api tokenpool.API, cost int) {
+	c, ok := ctx.Value(chargeKey{}).(*charge)
+	if !ok || cost <= 1 {
+		return
+	}
+
+	c.mu.Lock()
+	authorization := c.authorization
+	c.mu.Unlock()
+
+	l.reserve(api, authorization, cost-1)
+}
+
+// reserve books the next slot of the token for points and returns how long to wait for it
+func (l *Limiter) reserve(api tokenpool.API, authorization string, points int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	s := l.slot(api, authorization)
+
+	start := latest(now, s.next, l.state(api).blockedUntil)
+	interval := s.interval
+
+	if b := s.budget; b.known && b.resetAt.After(start) {
+		if b.remaining <= 0 {
+			start = b.resetAt
+		} else if float64(b.remaining) < float64(b.limit)*lowBudget {
+			interval = max(interval, b.resetAt.Sub(start)/time.Duration(b.remaining))
+		}
+	}
+
+	s.next = start.Add(interval * time.Duration(points))
+	return start.Sub(now)
+}
+
+// Observe adapts the pacing to the rate limit headers of a response. Only secondary
+// rate limits slow down, other failures like blocked repositories don't.
+func (l *Limiter) Observe(api tokenpool.API, authorization string, resp *http.Response) {
+	now := l.now()
+
+	// classified before locking, it reads the body
+	var ghErr *github.Error
+	secondary := errors.As(github.Peek(resp, now), &ghErr) && ghErr.Kind == github.SecondaryRateLimited
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.state(api)
+	token := l.slot(api, authorization)
+
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, resetErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+
+	if remainingErr == nil && resetErr == nil {
+		token.budget = budget{resetAt: time.Unix(reset, 0), limit: limit, remaining: remaining, known: true}
+	}
+
+	// a primary limit needs nothing else, the budget above blocks until the reset
+	switch {
+	case secondary:
+		s.blockedUntil = latest(s.blockedUntil, ghErr.ResetAt)
+		l.widen(api, s, token)
+	case resp.StatusCode < http.StatusBadRequest:
+		token.interval = max(l.minInterval(api), token.interval-token.interval/10)
+	}
+}
+
+func (l *Limiter) widen(api tokenpool.API, s *apiState, token *slot) {
+	token.interval = min(l.opts.MaxInterval, token.interval*2)
+
+	log.Warn().
+		Str("api", string(api)).
+		Time("blockedUntil", s.blockedUntil).
+		Dur("interval", token.interval).
+		Msg("secondary rate limit - slowing down")
+}
+
+// Transport waits for a slot before every request and observes every response
+func (l *Limiter) Transport(api tokenpool.API, wrapped http.RoundTripper) http.RoundTripper {
+	return &transport{limiter: l, api: api, wrapped: wrapped}
+}
+
+type transport struct {
+	limiter *Limiter
+	wrapped http.RoundTripper
+	api     tokenpool.API
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorization := req.Header.Get("Authorization")
+
+	if c, ok := req.Context().Value(chargeKey{}).(*charge); ok {
+		c.mu.Lock()
+		c.authorization = authorization
+		c.mu.Unlock()
+	}
+
+	if !github.IsUnmetered(req.Context()) {
+		err := t.limiter.Wait(req.Context(), t.api, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.wrapped.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.limiter.Observe(t.api, authorization, resp)
+	return resp, nil
+}
+
+func latest(times ...time.Time) time.Time {
+	result := times[0]
+	for _, t := range times[1:] {
+		if t.After(result) {
+			result = t
+		}
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/tokenpool"
+)
+
+func newTestLimiter(now *time.Time) *Limiter {
+	limiter := New(Options{
+		MinInterval: map[tokenpool.API]time.Duration{tokenpool.REST: time.Second},
+		MaxInterval: 4 * time.Second,
+	})
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func response(status int, headers map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+	for key, value := range headers {
+		resp.Header.Set(key, value)
+	}
+	return resp
+}
+
+func TestReserveSpacesRequests(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	limiter := newTestLimiter(&now)
+
+	for i, want := range []time.Duration{0, time.Second, 2 * time.Second} {
+		if got := limiter.reserve(tokenpool.REST, "token", 1); got != want {
+			t.Fatalf("Expected request %d to wait %s, got %s", i, want, got)
+		}
+	}
+
+	// a query that cost 3 points takes the slots of 3 requests of its token
+	ctx := Charged(context.Background())
+	ctx.Value(chargeKey{}).(*charge).authorization = "token"
+	limiter.Spend(ctx, tokenpool.REST, 3)
+	if got := limiter.reserve(tokenpool.REST, "token", 1); got != 5*time.Second {
+		t.Fatalf("Expected %s to equal 5s", got)
+	}
+
+	// tokens and APIs are paced independently, unknown APIs like REST
+	if got := limiter.reserve(tokenpool.REST, "other", 1); got != 0 {
+		t.Fatalf("Expected %s to equal 0s", got)
+	}
+	if got := limiter.reserve(tokenpool.GraphQL, "token", 1); got != 0 {
+		t.Fatalf("Expected %s to equal 0s", got)
+	}
+}
+
+func TestObserveSecondaryLimit(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	limiter := newTestLimiter(&now)
+
+	limiter.Observe(tokenpool.REST, "token", response(http.StatusForbidden, map[string]string{"Retry-After": "30"}))
+	if got := limiter.reserve(tokenpool.REST, "token", 1); got != 30*time.Second {
+		t.Fatalf("Expected %s to equal 30s", got)
+	}
+	// the spacing doubled
+	if got := limiter.reserve(tokenpool.REST, "token", 1); got != 32*time.Second {
+		t.Fatalf("Expected %s to equal 32s", got)
+	}
+
+	// without Retry-After GitHub wants a minute of rest, the spacing is capped
+	now = now.Add(time.Hour)
+	limiter.Observe(tokenpool.REST, "token", response(http.StatusTooManyRequests, map[string]string{"X-RateLimit-Remaining": "10"}))
+	limiter.reserve(tokenpool.REST, "token", 1)
+	if got := limiter.reserve(tokenpool.REST, "token", 1); got != time.Minute+4*time.Second {
+		t.Fatalf("Expected %s to equal 1m4s", got)
+	}
+
+	// other 403s like blocked repositories don't slow down
+	now = now.Add(time.Hour)
+	blocked := response(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"})
+	blocked.Body = io.NopCloser(strings.NewReader(`{"message":"Repository access blocked"}`))
+	limiter.Observe(tokenpool.REST, "token", blocked)
+	if got := limiter.reserve(tokenpool.REST, "token", 1); got != 0 {
+		t.Fatalf("Expected %s to equal 0s", got)
+	}
+
+	// successful responses narrow the spacing again, but not below the minimum
+	for i := 0; i < 100; i++ {
+		limiter.Observe(tokenpool.REST, "token", response(http.StatusOK, nil))
+	}
+	if got := limiter.slot(tokenpool.REST, "token").interval; got != time.Second {
+		t.Fatalf("Expected %s to equal 1s", got)
+	}
+}
+
+func TestObservePrimaryLimit(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	limiter := newTestLimiter(&now)
+
+	// an exhausted token waits for its reset, other tokens go on
+	limiter.Observe(tokenpool.REST, "token-a", response(http.StatusForbidden, map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+	}))
+	if got := limiter.reserve(tokenpool.REST, "token-a", 1); got != time.Hour {
+		t.Fatalf("Expected %s to equal 1h", got)
+	}
+	if got := limiter.reserve(tokenpool.REST, "token-b", 1); got != 0 {
+		t.Fatalf("Expected %s to equal 0s", got)
+	}
+
+	// a low budget is spread until the reset
+	limiter = newTestLimiter(&now)
+	limiter.Observe(tokenpool.REST, "token-b", response(http.StatusOK, map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "60",
+		"X-RateLimit-Reset":     strconv.FormatInt(now.Add(2*time.Minute).Unix(), 10),
+	}))
+	limiter.reserve(tokenpool.REST, "token-b", 1)
+	if got := limiter.reserve(tokenpool.REST, "token-b", 1); got != 2*time.Second {
+		t.Fatalf("Expected %s to equal 2s", got)
+	}
+	limiter.reserve(tokenpool.REST, "token-c", 1)
+	if got := limiter.reserve(tokenpool.REST, "token-c", 1); got != time.Second {
+		t.Fatalf("Expected %s to equal 1s", got)
+	}
+}
+
+func TestTransportWaitsForSecondaryLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: New(DefaultOptions).Transport(tokenpool.REST, http.DefaultTransport)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Do(req)
+	if err == nil {
+		t.Fatal("Expected the blocked request to be cancelled")
+	}
+}
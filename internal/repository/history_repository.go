@@ -10,9 +10,11 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// ViewRefreshedChannel gets notified with the name of a view after it was refreshed
+const ViewRefreshedChannel = "trend_views_refreshed"
+
 type HistoryRepository struct {
-	db  *db.Database
-	ctx context.Context
+	db *db.Database
 }
 
 type StarHistoryInput struct {
@@ -21,25 +23,50 @@ type StarHistoryInput struct {
 	Id        int
 }
 
-func NewHistoryRepository(ctx context.Context, db *db.Database) *HistoryRepository {
+type StarHistoryPoint struct {
+	Date      time.Time
+	StarCount int
+}
+
+type HistoryBucket string
+
+const (
+	BucketDay   HistoryBucket = "day"
+	BucketWeek  HistoryBucket = "week"
+	BucketMonth HistoryBucket = "month"
+)
+
+func NewHistoryRepository(db *db.Database) *HistoryRepository {
 	return &HistoryRepository{
-		db:  db,
-		ctx: ctx,
+		db: db,
+	}
+}
+
+func ParseHistoryBucket(bucket string) (HistoryBucket, error) {
+	switch HistoryBucket(bucket) {
+	case BucketDay, BucketWeek, BucketMonth:
+		return HistoryBucket(bucket), nil
 	}
+	return "", fmt.Errorf("invalid history bucket %s", bucket)
+}
+
+// interval returns the timescale bucket width
+func (b HistoryBucket) interval() string {
+	return "1 " + string(b)
 }
 
-func (r *HistoryRepository) BatchUpsert(inputs []StarHistoryInput) error {
+func (r *HistoryRepository) BatchUpsert(ctx context.Context, inputs []StarHistoryInput) error {
 	const batchSize = 10_000
 
 	if len(inputs) == 0 {
 		return fmt.Errorf("empty inputs")
 	}
 
-	tx, err := r.db.Pool.Begin(r.ctx)
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer tx.Rollback(r.ctx)
+	defer tx.Rollback(ctx)
 
 	for start := 0; start < len(inputs); start += batchSize {
 		end := start + batchSize
@@ -66,7 +93,7 @@ func (r *HistoryRepository) BatchUpsert(inputs []StarHistoryInput) error {
 			return fmt.Errorf("failed to build SQL: %w", err)
 		}
 
-		if _, err := tx.Exec(r.ctx, sql, args...); err != nil {
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
 			return fmt.Errorf("failed to execute upsert: %w", err)
 		}
 	}
@@ -80,18 +107,18 @@ func (r *HistoryRepository) BatchUpsert(inputs []StarHistoryInput) error {
 		return fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	if _, err = tx.Exec(r.ctx, sql, args...); err != nil {
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
 		return fmt.Errorf("failed to update repository: %w", err)
 	}
 
-	if err := tx.Commit(r.ctx); err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (r *HistoryRepository) CreateSnapshot() error {
+func (r *HistoryRepository) CreateSnapshot(ctx context.Context) error {
 	sql, args, err := sq.Insert("stars_history_hyper").
 		Columns("repository_id", "star_count", "date").
 		Select(
@@ -107,7 +134,7 @@ func (r *HistoryRepository) CreateSnapshot() error {
 		return fmt.Errorf("error building SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -115,10 +142,122 @@ func (r *HistoryRepository) CreateSnapshot() error {
 	return nil
 }
 
-func (r *HistoryRepository) RefreshView(view string) error {
+// RefreshView refreshes a materialized view and records when in view_refreshes
+func (r *HistoryRepository) RefreshView(ctx context.Context, view string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	sqlStr := fmt.Sprintf("REFRESH MATERIALIZED VIEW %s", pgx.Identifier{view}.Sanitize())
 
-	_, err := r.db.Pool.Exec(r.ctx, sqlStr)
+	_, err = tx.Exec(ctx, sqlStr)
+	if err != nil {
+		return err
+	}
+
+	// NOW() is the start of the transaction, clock_timestamp() the time after the refresh
+	sql, args, err := sq.
+		Insert("view_refreshes").
+		Columns("view", "refreshed_at", "duration_ms").
+		Values(view, sq.Expr("clock_timestamp()"), sq.Expr("(EXTRACT(EPOCH FROM clock_timestamp() - NOW()) * 1000)::INT")).
+		Suffix(`
+			ON CONFLICT (view) DO UPDATE SET
+			refreshed_at = EXCLUDED.refreshed_at,
+			duration_ms = EXCLUDED.duration_ms
+		`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to record view refresh: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type ViewRefresh struct {
+	RefreshedAt time.Time
+	View        string
+	Duration    time.Duration
+}
+
+func (r *HistoryRepository) GetViewRefreshes(ctx context.Context) ([]ViewRefresh, error) {
+	sql, args, err := sq.
+		Select("view", "refreshed_at", "duration_ms").
+		From("view_refreshes").
+		OrderBy("view").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	refreshes := []ViewRefresh{}
+	for rows.Next() {
+		var refresh ViewRefresh
+		var durationMs int
+		err := rows.Scan(&refresh.View, &refresh.RefreshedAt, &durationMs)
+		if err != nil {
+			return refreshes, err
+		}
+		refresh.Duration = time.Duration(durationMs) * time.Millisecond
+		refreshes = append(refreshes, refresh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return refreshes, err
+	}
+
+	return refreshes, nil
+}
+
+// GetLastSnapshotDate returns nil if no snapshot was taken yet
+func (r *HistoryRepository) GetLastSnapshotDate(ctx context.Context) (*time.Time, error) {
+	var date *time.Time
+
+	err := r.db.Pool.QueryRow(ctx, "SELECT MAX(date) FROM stars_history_hyper").Scan(&date)
+	if err != nil {
+		return nil, fmt.Errorf("querying last snapshot: %w", err)
+	}
+
+	return date, nil
+}
+
+// CountBrokenByTier counts the repositories waiting in history_repairs
+func (r *HistoryRepository) CountBrokenByTier(ctx context.Context) ([]StarTier, error) {
+	sql, args, err := countByStarTier(
+		sq.Select().From("history_repairs h").Join("repositories r ON r.id = h.repository_id"),
+		"r.star_count",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+
+	return scanStarTiers(rows)
+}
+
+func (r *HistoryRepository) NotifyViewRefreshed(ctx context.Context, view string) error {
+	_, err := r.db.Pool.Exec(ctx, "SELECT pg_notify($1, $2)", ViewRefreshedChannel, view)
 	if err != nil {
 		return err
 	}
@@ -126,7 +265,7 @@ func (r *HistoryRepository) RefreshView(view string) error {
 	return nil
 }
 
-func (r *HistoryRepository) DeleteForRepo(id int) error {
+func (r *HistoryRepository) DeleteForRepo(ctx context.Context, id int) error {
 	sql, args, err := sq.
 		Delete("stars_history_hyper").
 		Where(sq.Eq{"repository_id": id}).
@@ -136,7 +275,7 @@ func (r *HistoryRepository) DeleteForRepo(id int) error {
 		return err
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -144,7 +283,7 @@ func (r *HistoryRepository) DeleteForRepo(id int) error {
 	return nil
 }
 
-func (r *HistoryRepository) GetBrokenRepos(maxStarCount int) ([]BrokenRepo, error) {
+func (r *HistoryRepository) GetBrokenRepos(ctx context.Context, maxStarCount int) ([]BrokenRepo, error) {
 	sql, args, err := sq.
 		Select("r.id", "r.github_id", "r.star_count", "h.until_date", "r.name_with_owner").
 		From("history_repairs h").
@@ -156,7 +295,7 @@ func (r *HistoryRepository) GetBrokenRepos(maxStarCount int) ([]BrokenRepo, erro
 		return nil, fmt.Errorf("building SQL: %w", err)
 	}
 
-	rows, err := r.db.Pool.Query(r.ctx, sql, args...)
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying rows: %w", err)
 	}
@@ -179,7 +318,7 @@ func (r *HistoryRepository) GetBrokenRepos(maxStarCount int) ([]BrokenRepo, erro
 	return repos, nil
 }
 
-func (r *HistoryRepository) RemoveBrokenRepo(id int) error {
+func (r *HistoryRepository) RemoveBrokenRepo(ctx context.Context, id int) error {
 	sql, args, err := sq.
 		Delete("history_repairs").
 		Where(sq.Eq{"repository_id": id}).
@@ -189,10 +328,55 @@ func (r *HistoryRepository) RemoveBrokenRepo(id int) error {
 		return fmt.Errorf("building SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// GetStarHistory returns the last star count of every bucket between from and to (inclusive)
+func (r *HistoryRepository) GetStarHistory(ctx context.Context, id int, from time.Time, to time.Time, bucket HistoryBucket) ([]StarHistoryPoint, error) {
+	if _, err := ParseHistoryBucket(string(bucket)); err != nil {
+		return nil, err
+	}
+
+	sql, args, err := sq.
+		Select().
+		Column(sq.Expr("time_bucket(?::INTERVAL, date) AS bucket", bucket.interval())).
+		Column("last(star_count, date)").
+		From("stars_history_hyper").
+		Where(sq.Eq{"repository_id": id}).
+		Where(sq.GtOrEq{"date": from}).
+		Where(sq.LtOrEq{"date": to}).
+		GroupBy("bucket").
+		OrderBy("bucket").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	points := []StarHistoryPoint{}
+	for rows.Next() {
+		var point StarHistoryPoint
+		err := rows.Scan(&point.Date, &point.StarCount)
+		if err != nil {
+			return points, err
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return points, err
+	}
+
+	return points, nil
+}
@@ -30,17 +30,17 @@ func TestHistoryRepository(t *testing.T) {
 		}
 		defer pool.Close()
 
-		hRepo := NewHistoryRepository(ctx, &db.Database{Pool: pool})
-		rRepo := NewRepoRepository(ctx, &db.Database{Pool: pool})
+		hRepo := NewHistoryRepository(&db.Database{Pool: pool})
+		rRepo := NewRepoRepository(&db.Database{Pool: pool})
 
-		err = hRepo.CreateSnapshot()
+		err = hRepo.CreateSnapshot(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		repoId := 1
 
-		starCount, err := rRepo.GetStarCount(repoId, time.Now())
+		starCount, err := rRepo.GetStarCount(ctx, repoId, time.Now())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -63,12 +63,12 @@ func TestHistoryRepository(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err = hRepo.CreateSnapshot()
+		err = hRepo.CreateSnapshot(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		starCount, err = rRepo.GetStarCount(repoId, time.Now())
+		starCount, err = rRepo.GetStarCount(ctx, repoId, time.Now())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -91,12 +91,12 @@ func TestHistoryRepository(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		err = hRepo.CreateSnapshot()
+		err = hRepo.CreateSnapshot(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		starCount, err = rRepo.GetStarCount(repoId, time.Now())
+		starCount, err = rRepo.GetStarCount(ctx, repoId, time.Now())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -104,4 +104,41 @@ func TestHistoryRepository(t *testing.T) {
 			t.Fatalf("Expected %d to equal 500", starCount)
 		}
 	})
+
+	t.Run("Test star history is bucketed by week", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		hRepo := NewHistoryRepository(&db.Database{Pool: pool})
+
+		// 2024-07-01 is a monday
+		err = hRepo.BatchUpsert(ctx, []StarHistoryInput{
+			{Id: 1, Date: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), StarCount: 10},
+			{Id: 1, Date: time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC), StarCount: 20},
+			{Id: 1, Date: time.Date(2024, 7, 9, 0, 0, 0, 0, time.UTC), StarCount: 30},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		points, err := hRepo.GetStarHistory(ctx, 1, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 31, 0, 0, 0, 0, time.UTC), BucketWeek)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(points) != 2 {
+			t.Fatalf("Expected %d points to equal 2", len(points))
+		}
+		if points[0].StarCount != 20 || points[1].StarCount != 30 {
+			t.Fatalf("Expected star counts 20 and 30, got %v", points)
+		}
+	})
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -16,8 +17,7 @@ const (
 )
 
 type RepoRepository struct {
-	db  *db.Database
-	ctx context.Context
+	db *db.Database
 }
 
 type Repo struct {
@@ -35,6 +35,21 @@ type BrokenRepo struct {
 	StarCount     int
 }
 
+// RepoDetails is everything the repositories table knows about a repository
+type RepoDetails struct {
+	// RepairUntil is set while the repository waits in history_repairs
+	RepairUntil     *time.Time
+	GithubId        string
+	NameWithOwner   string
+	Description     string
+	PrimaryLanguage string
+	Languages       []string
+	Id              int
+	StarCount       int
+	ForkCount       int
+	HistoryMissing  bool
+}
+
 type RepoInput struct {
 	GithubId        string
 	Name            string
@@ -53,14 +68,13 @@ type LanguageInput struct {
 
 type SortOrder string
 
-func NewRepoRepository(ctx context.Context, db *db.Database) *RepoRepository {
+func NewRepoRepository(db *db.Database) *RepoRepository {
 	return &RepoRepository{
-		db:  db,
-		ctx: ctx,
+		db: db,
 	}
 }
 
-func (r *RepoRepository) UpsertMany(repos []RepoInput) error {
+func (r *RepoRepository) UpsertMany(ctx context.Context, repos []RepoInput) error {
 	query := sq.Insert("repositories").
 		Columns(
 			"github_id",
@@ -102,7 +116,7 @@ func (r *RepoRepository) UpsertMany(repos []RepoInput) error {
 		return fmt.Errorf("error building SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -110,7 +124,7 @@ func (r *RepoRepository) UpsertMany(repos []RepoInput) error {
 	return nil
 }
 
-func (r *RepoRepository) FindNextMissing(maxStarCount int, order SortOrder) (Repo, error) {
+func (r *RepoRepository) FindNextMissing(ctx context.Context, maxStarCount int, order SortOrder) (Repo, error) {
 	var repo Repo
 
 	if order != OrderAsc && order != OrderDesc {
@@ -130,7 +144,7 @@ func (r *RepoRepository) FindNextMissing(maxStarCount int, order SortOrder) (Rep
 		return repo, err
 	}
 
-	err = r.db.Pool.QueryRow(r.ctx, sql, args...).Scan(&repo.Id, &repo.GithubId, &repo.StarCount, &repo.NameWithOwner)
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&repo.Id, &repo.GithubId, &repo.StarCount, &repo.NameWithOwner)
 	if err != nil {
 		return repo, err
 	}
@@ -138,7 +152,7 @@ func (r *RepoRepository) FindNextMissing(maxStarCount int, order SortOrder) (Rep
 	return repo, nil
 }
 
-func (r *RepoRepository) UpsertLanguages(languages []LanguageInput) error {
+func (r *RepoRepository) UpsertLanguages(ctx context.Context, languages []LanguageInput) error {
 	if len(languages) == 0 {
 		return nil
 	}
@@ -160,7 +174,7 @@ func (r *RepoRepository) UpsertLanguages(languages []LanguageInput) error {
 		return fmt.Errorf("error building SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -168,7 +182,7 @@ func (r *RepoRepository) UpsertLanguages(languages []LanguageInput) error {
 	return nil
 }
 
-func (r *RepoRepository) Delete(id int) error {
+func (r *RepoRepository) Delete(ctx context.Context, id int) error {
 	sql, args, err := sq.
 		Delete("repositories").
 		Where(sq.Eq{"id": id}).
@@ -178,7 +192,7 @@ func (r *RepoRepository) Delete(id int) error {
 		return fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -186,7 +200,7 @@ func (r *RepoRepository) Delete(id int) error {
 	return nil
 }
 
-func (r *RepoRepository) MarkAsDone(id int) error {
+func (r *RepoRepository) MarkAsDone(ctx context.Context, id int) error {
 	sql, args, err := sq.
 		Update("repositories").
 		Set("history_missing", false).
@@ -197,7 +211,7 @@ func (r *RepoRepository) MarkAsDone(id int) error {
 		return fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -205,7 +219,7 @@ func (r *RepoRepository) MarkAsDone(id int) error {
 	return nil
 }
 
-func (r *RepoRepository) GetStarCount(id int, date time.Time) (int, error) {
+func (r *RepoRepository) GetStarCount(ctx context.Context, id int, date time.Time) (int, error) {
 	var starCount int
 
 	sql, args, err := sq.
@@ -221,7 +235,7 @@ func (r *RepoRepository) GetStarCount(id int, date time.Time) (int, error) {
 		return starCount, fmt.Errorf("failed to build SQL: %w", err)
 	}
 
-	err = r.db.Pool.QueryRow(r.ctx, sql, args...).Scan(&starCount)
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&starCount)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return starCount, nil
@@ -231,3 +245,109 @@ func (r *RepoRepository) GetStarCount(id int, date time.Time) (int, error) {
 
 	return starCount, nil
 }
+
+func (r *RepoRepository) FindByNameWithOwner(ctx context.Context, nameWithOwner string) (Repo, error) {
+	var repo Repo
+
+	sql, args, err := sq.
+		Select("id", "github_id", "star_count", "name_with_owner").
+		From("repositories").
+		Where(sq.Eq{"name_with_owner": nameWithOwner}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return repo, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&repo.Id, &repo.GithubId, &repo.StarCount, &repo.NameWithOwner)
+	if err != nil {
+		return repo, err
+	}
+
+	return repo, nil
+}
+
+func (r *RepoRepository) GetDetails(ctx context.Context, nameWithOwner string) (RepoDetails, error) {
+	var details RepoDetails
+
+	sql, args, err := sq.
+		Select(
+			"r.id",
+			"r.github_id",
+			"r.name_with_owner",
+			"COALESCE(r.description, '')",
+			"COALESCE(r.primary_language, '')",
+			"r.languages",
+			"r.star_count",
+			"r.fork_count",
+			"r.history_missing",
+			"h.until_date",
+		).
+		From("repositories r").
+		LeftJoin("history_repairs h ON h.repository_id = r.id").
+		Where(sq.Eq{"r.name_with_owner": nameWithOwner}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return details, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(
+		&details.Id,
+		&details.GithubId,
+		&details.NameWithOwner,
+		&details.Description,
+		&details.PrimaryLanguage,
+		&details.Languages,
+		&details.StarCount,
+		&details.ForkCount,
+		&details.HistoryMissing,
+		&details.RepairUntil,
+	)
+	if err != nil {
+		return details, err
+	}
+
+	return details, nil
+}
+
+// FindLanguage looks up the spelling of a language case-insensitively, e.g. go => Go
+func (r *RepoRepository) FindLanguage(ctx context.Context, name string) (string, error) {
+	var language string
+
+	sql, args, err := sq.
+		Select("id").
+		From("languages").
+		Where(sq.Eq{"LOWER(id)": strings.ToLower(name)}).
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return language, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&language)
+	if err != nil {
+		return language, err
+	}
+
+	return language, nil
+}
+
+// CountMissingByTier counts the repositories whose star history was never fetched
+func (r *RepoRepository) CountMissingByTier(ctx context.Context) ([]StarTier, error) {
+	sql, args, err := countByStarTier(
+		sq.Select().From("repositories").Where(sq.Eq{"history_missing": true}),
+		"star_count",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+
+	return scanStarTiers(rows)
+}
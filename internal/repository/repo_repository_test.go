@@ -29,9 +29,9 @@ func TestRepoRepository(t *testing.T) {
 		}
 		defer pool.Close()
 
-		r := NewRepoRepository(ctx, &database.Database{Pool: pool})
+		r := NewRepoRepository(&database.Database{Pool: pool})
 
-		repo, err := r.FindNextMissing(1_000_000, OrderAsc)
+		repo, err := r.FindNextMissing(ctx, 1_000_000, OrderAsc)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -53,9 +53,9 @@ func TestRepoRepository(t *testing.T) {
 		}
 		defer pool.Close()
 
-		r := NewRepoRepository(ctx, &database.Database{Pool: pool})
+		r := NewRepoRepository(&database.Database{Pool: pool})
 
-		repo, err := r.FindNextMissing(1_000_000, OrderDesc)
+		repo, err := r.FindNextMissing(ctx, 1_000_000, OrderDesc)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -77,7 +77,7 @@ func TestRepoRepository(t *testing.T) {
 		}
 		defer pool.Close()
 
-		r := NewRepoRepository(ctx, &database.Database{Pool: pool})
+		r := NewRepoRepository(&database.Database{Pool: pool})
 		repos := []RepoInput{
 			{GithubId: "R_kg0001", Name: "glup3", NameWithOwner: "glup3/repo0001", StarCount: 922, ForkCount: 0, Languages: []string{}, PrimaryLanguage: "Go", Description: ""},
 			{GithubId: "R_kg0002", Name: "glup3", NameWithOwner: "glup3/repo0002", StarCount: 300, ForkCount: 0, Languages: []string{}, PrimaryLanguage: "Go", Description: ""},
@@ -92,7 +92,7 @@ func TestRepoRepository(t *testing.T) {
 			t.Fatalf("expected %d to equal 400000", starCount)
 		}
 
-		err = r.UpsertMany(repos)
+		err = r.UpsertMany(ctx, repos)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -105,6 +105,38 @@ func TestRepoRepository(t *testing.T) {
 			t.Fatalf("expected %d to equal 410233", starCount)
 		}
 	})
+
+	t.Run("Test getting details includes pending repair", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		r := NewRepoRepository(&database.Database{Pool: pool})
+
+		_, err = pool.Exec(ctx, "INSERT INTO history_repairs (repository_id, until_date) VALUES (3, '2024-07-01')")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		details, err := r.GetDetails(ctx, "glup3/repo0003")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if details.Id != 3 || details.HistoryMissing {
+			t.Fatalf("Expected repo 3 with history, got %v", details)
+		}
+		if details.RepairUntil == nil || details.RepairUntil.Format("2006-01-02") != "2024-07-01" {
+			t.Fatalf("Expected repair until 2024-07-01, got %v", details.RepairUntil)
+		}
+	})
 }
 
 func getStarCount(ctx context.Context, pool *pgxpool.Pool, githubId string) (int, error) {
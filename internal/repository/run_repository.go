@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/glup3/TrendyGitHub/internal/db"
+)
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	// RunCancelled marks runs stopped by a shutdown, their progress up to then is saved
+	RunCancelled RunStatus = "cancelled"
+	// RunSkipped marks runs that did not start because another process held the job lock
+	RunSkipped RunStatus = "skipped"
+)
+
+type RunRepository struct {
+	db *db.Database
+}
+
+// RunResult is what a job reports when it finishes
+type RunResult struct {
+	Status         RunStatus
+	Cursor         string
+	Error          string
+	GraphqlUnits   int
+	RestUnits      int
+	ReposProcessed int
+}
+
+type JobRun struct {
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	Mode       string
+	RunResult
+	Id int
+}
+
+func NewRunRepository(db *db.Database) *RunRepository {
+	return &RunRepository{
+		db: db,
+	}
+}
+
+func (r *RunRepository) Start(ctx context.Context, mode string) (int, error) {
+	sql, args, err := sq.
+		Insert("job_runs").
+		Columns("mode", "status").
+		Values(mode, RunRunning).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("building SQL: %w", err)
+	}
+
+	var id int
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("inserting job run: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *RunRepository) Finish(ctx context.Context, id int, result RunResult) error {
+	sql, args, err := sq.
+		Update("job_runs").
+		Set("status", result.Status).
+		Set("finished_at", sq.Expr("NOW()")).
+		Set("graphql_units", result.GraphqlUnits).
+		Set("rest_units", result.RestUnits).
+		Set("repos_processed", result.ReposProcessed).
+		Set("cursor", sq.Expr("NULLIF(?, '')", result.Cursor)).
+		Set("error", sq.Expr("NULLIF(?, '')", result.Error)).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("building SQL: %w", err)
+	}
+
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("updating job run: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the latest runs first, an empty mode lists all modes
+func (r *RunRepository) List(ctx context.Context, mode string, limit int) ([]JobRun, error) {
+	builder := sq.
+		Select(
+			"id",
+			"mode",
+			"status",
+			"started_at",
+			"finished_at",
+			"graphql_units",
+			"rest_units",
+			"repos_processed",
+			"COALESCE(cursor, '')",
+			"COALESCE(error, '')",
+		).
+		From("job_runs").
+		OrderBy("started_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	if mode != "" {
+		builder = builder.Where(sq.Eq{"mode": mode})
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []JobRun{}
+	for rows.Next() {
+		var run JobRun
+		err := rows.Scan(
+			&run.Id,
+			&run.Mode,
+			&run.Status,
+			&run.StartedAt,
+			&run.FinishedAt,
+			&run.GraphqlUnits,
+			&run.RestUnits,
+			&run.ReposProcessed,
+			&run.Cursor,
+			&run.Error,
+		)
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return runs, err
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestRunRepository(t *testing.T) {
+	connString, cleanup, restore, err := testutil.SetupPostgresContainer()
+	if err != nil {
+		t.Fatalf("failed to set up test container: %v", err)
+	}
+	defer cleanup()
+
+	t.Run("Test recording a finished run", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		repo := NewRunRepository(&database.Database{Pool: pool})
+
+		id, err := repo.Start(ctx, "search")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = repo.Start(ctx, "history")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = repo.Finish(ctx, id, RunResult{Status: RunSucceeded, GraphqlUnits: 60, ReposProcessed: 1000, Cursor: "12345"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		runs, err := repo.List(ctx, "search", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(runs) != 1 {
+			t.Fatalf("Expected %d runs to equal 1", len(runs))
+		}
+		if runs[0].Status != RunSucceeded || runs[0].FinishedAt == nil {
+			t.Fatalf("Expected a finished run, got %v", runs[0])
+		}
+		if runs[0].GraphqlUnits != 60 || runs[0].ReposProcessed != 1000 {
+			t.Fatalf("Expected 60 units and 1000 repos, got %v", runs[0])
+		}
+		if runs[0].Cursor != "12345" || runs[0].Error != "" {
+			t.Fatalf("Expected cursor 12345 without error, got %v", runs[0])
+		}
+
+		runs, err = repo.List(ctx, "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 2 {
+			t.Fatalf("Expected %d runs to equal 2", len(runs))
+		}
+	})
+}
@@ -9,28 +9,23 @@ import (
 )
 
 type SettingsRepository struct {
-	db  *db.Database
-	ctx context.Context
+	db *db.Database
 }
 
 type Settings struct {
-	ID                     int
-	CurrentMaxStarCount    int
-	MinStarCount           int
-	TimeoutSecondsPrevent  int
-	TimeoutSecondsExceeded int
-	TimeoutMaxUnits        int
-	IsEnabled              bool
+	ID                  int
+	CurrentMaxStarCount int
+	MinStarCount        int
+	IsEnabled           bool
 }
 
-func NewSettingsRepository(ctx context.Context, db *db.Database) *SettingsRepository {
+func NewSettingsRepository(db *db.Database) *SettingsRepository {
 	return &SettingsRepository{
-		db:  db,
-		ctx: ctx,
+		db: db,
 	}
 }
 
-func (r *SettingsRepository) Load() (Settings, error) {
+func (r *SettingsRepository) Load(ctx context.Context) (Settings, error) {
 	var settings Settings
 
 	sql, args, err := sq.
@@ -38,9 +33,6 @@ func (r *SettingsRepository) Load() (Settings, error) {
 			"id",
 			"current_max_star_count",
 			"min_star_count",
-			"timeout_seconds_prevent",
-			"timeout_seconds_exceeded",
-			"timeout_max_units",
 			"enabled",
 		).
 		From("settings").
@@ -52,14 +44,11 @@ func (r *SettingsRepository) Load() (Settings, error) {
 		return settings, fmt.Errorf("error building SQL: %w", err)
 	}
 
-	err = r.db.Pool.QueryRow(r.ctx, sql, args...).
+	err = r.db.Pool.QueryRow(ctx, sql, args...).
 		Scan(
 			&settings.ID,
 			&settings.CurrentMaxStarCount,
 			&settings.MinStarCount,
-			&settings.TimeoutSecondsPrevent,
-			&settings.TimeoutSecondsExceeded,
-			&settings.TimeoutMaxUnits,
 			&settings.IsEnabled,
 		)
 
@@ -70,7 +59,7 @@ func (r *SettingsRepository) Load() (Settings, error) {
 	return settings, nil
 }
 
-func (r *SettingsRepository) UpdateStarCountCursor(newCount int, settingsID int) error {
+func (r *SettingsRepository) UpdateStarCountCursor(ctx context.Context, newCount int, settingsID int) error {
 	sql, args, err := sq.
 		Update("settings").
 		Set("current_max_star_count", newCount).
@@ -81,7 +70,7 @@ func (r *SettingsRepository) UpdateStarCountCursor(newCount int, settingsID int)
 		return fmt.Errorf("error building SQL: %v", err)
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return fmt.Errorf("error updating current max star count in SQL: %v", err)
 	}
@@ -90,7 +79,7 @@ func (r *SettingsRepository) UpdateStarCountCursor(newCount int, settingsID int)
 
 }
 
-func (r *SettingsRepository) ResetStarCountCursor(settingsID int) error {
+func (r *SettingsRepository) ResetStarCountCursor(ctx context.Context, settingsID int) error {
 	sql, args, err := sq.
 		Update("settings").
 		Set("current_max_star_count", 1_000_000).
@@ -101,7 +90,7 @@ func (r *SettingsRepository) ResetStarCountCursor(settingsID int) error {
 		return err
 	}
 
-	_, err = r.db.Pool.Exec(r.ctx, sql, args...)
+	_, err = r.db.Pool.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
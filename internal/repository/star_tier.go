@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// StarTier counts repositories with at most MaxStars stars, 0 has no upper bound
+type StarTier struct {
+	Label    string
+	MaxStars int
+	Count    int
+}
+
+// starTierBounds match the star counts the jobs use to pick the REST or GraphQL API
+var starTierBounds = []int{1_000, 10_000, 40_000, 100_000}
+
+// countByStarTier groups the rows of builder by the star count in column
+func countByStarTier(builder sq.SelectBuilder, column string) (string, []any, error) {
+	// width_bucket treats bounds as inclusive lower limits
+	lowerBounds := make([]int, len(starTierBounds))
+	for i, bound := range starTierBounds {
+		lowerBounds[i] = bound + 1
+	}
+
+	return builder.
+		Column(sq.Expr("width_bucket("+column+", ?::INT[]) AS tier", lowerBounds)).
+		Column("COUNT(*)").
+		GroupBy("tier").
+		OrderBy("tier").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+}
+
+func scanStarTiers(rows pgx.Rows) ([]StarTier, error) {
+	defer rows.Close()
+
+	tiers := make([]StarTier, len(starTierBounds)+1)
+	for i := range tiers {
+		if i < len(starTierBounds) {
+			tiers[i].MaxStars = starTierBounds[i]
+			tiers[i].Label = fmt.Sprintf("<= %d", starTierBounds[i])
+		} else {
+			tiers[i].Label = fmt.Sprintf("> %d", starTierBounds[i-1])
+		}
+	}
+
+	for rows.Next() {
+		var tier, count int
+		err := rows.Scan(&tier, &count)
+		if err != nil {
+			return tiers, err
+		}
+		tiers[tier].Count = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return tiers, err
+	}
+
+	return tiers, nil
+}
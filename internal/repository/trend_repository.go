@@ -0,0 +1,477 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// minStarsDiff is the amount of stars the trend views require to list a repository
+const minStarsDiff = 10
+
+const (
+	PeriodDaily   TrendPeriod = "daily"
+	PeriodWeekly  TrendPeriod = "weekly"
+	PeriodMonthly TrendPeriod = "monthly"
+)
+
+var TrendPeriods = []TrendPeriod{PeriodDaily, PeriodWeekly, PeriodMonthly}
+
+type TrendRepository struct {
+	db *db.Database
+}
+
+type TrendPeriod string
+
+type Trend struct {
+	NameWithOwner   string
+	Description     string
+	PrimaryLanguage string
+	LanguageColor   string
+	Id              int
+	StarCount       int
+	StarsDiff       int
+	// StartStarCount is the star count at the start of the period
+	StartStarCount int
+}
+
+type TrendQuery struct {
+	Period TrendPeriod
+	// Language filters by primary language
+	Language string
+	// Uses filters by membership in the languages of a repository
+	Uses   string
+	Limit  int
+	Offset int
+}
+
+// MaxAsOfDays bounds custom windows of GetTrendsAsOf, longer ones scan too much history
+const MaxAsOfDays = 366
+
+type TrendAsOfQuery struct {
+	Date time.Time
+	// Window is a postgres interval like '1 week'
+	Window   string
+	Language string
+	Uses     string
+	Limit    int
+	Offset   int
+}
+
+type Ranking struct {
+	Date      time.Time
+	Rank      int
+	StarsDiff int
+}
+
+type Climber struct {
+	NameWithOwner string
+	Id            int
+	// FromRank is 0 if the repository wasn't ranked
+	FromRank int
+	ToRank   int
+	Climbed  int
+}
+
+type TrendLanguage struct {
+	Name     string
+	Hexcolor string
+	Count    int
+}
+
+func NewTrendRepository(db *db.Database) *TrendRepository {
+	return &TrendRepository{
+		db: db,
+	}
+}
+
+func ParseTrendPeriod(period string) (TrendPeriod, error) {
+	for _, p := range TrendPeriods {
+		if string(p) == period {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid trend period %s", period)
+}
+
+// View returns the materialized view backing the period
+func (p TrendPeriod) View() string {
+	return "trend_" + string(p)
+}
+
+// Window returns how far the view of the period looks back, see 009_add_trend_views.up.sql
+func (p TrendPeriod) Window() string {
+	switch p {
+	case PeriodDaily:
+		return "2 day"
+	case PeriodWeekly:
+		return "1 week"
+	default:
+		return "1 month"
+	}
+}
+
+func (r *TrendRepository) GetTrends(ctx context.Context, query TrendQuery) ([]Trend, error) {
+	if _, err := ParseTrendPeriod(string(query.Period)); err != nil {
+		return nil, err
+	}
+
+	builder := selectTrends("r.star_count").
+		From(pgx.Identifier{query.Period.View()}.Sanitize() + " t")
+
+	return r.queryTrends(ctx, filterTrends(builder, query.Language, query.Uses), query.Limit, query.Offset)
+}
+
+// GetTrendsAsOf computes the trends like the views would have on query.Date,
+// star counts are the ones of that date
+func (r *TrendRepository) GetTrendsAsOf(ctx context.Context, query TrendAsOfQuery) ([]Trend, error) {
+	history := sq.
+		Select(
+			"repository_id",
+			"last(star_count, date) AS last",
+			"last(star_count, date) - first(star_count, date) AS stars_diff",
+		).
+		From("stars_history_hyper").
+		Where("date >= ?::DATE - ?::INTERVAL", query.Date, query.Window).
+		Where(sq.LtOrEq{"date": query.Date}).
+		GroupBy("repository_id").
+		Having("last(star_count, date) - first(star_count, date) > ?", minStarsDiff)
+
+	builder := selectTrends("t.last").FromSelect(history, "t")
+
+	return r.queryTrends(ctx, filterTrends(builder, query.Language, query.Uses), query.Limit, query.Offset)
+}
+
+// Growth is the percentage the stars grew by within the period
+func (t Trend) Growth() float64 {
+	if t.StartStarCount <= 0 {
+		return 0
+	}
+	return float64(t.StarsDiff) * 100 / float64(t.StartStarCount)
+}
+
+func selectTrends(starCountColumn string) sq.SelectBuilder {
+	return sq.Select(
+		"r.id",
+		"r.name_with_owner",
+		"COALESCE(r.description, '')",
+		"COALESCE(r.primary_language, '')",
+		"COALESCE(l.hexcolor, '')",
+		starCountColumn,
+		"t.stars_diff",
+		"t.last - t.stars_diff",
+	)
+}
+
+func filterTrends(builder sq.SelectBuilder, language string, uses string) sq.SelectBuilder {
+	builder = builder.
+		Join("repositories r ON r.id = t.repository_id").
+		LeftJoin("languages l ON l.id = r.primary_language")
+
+	if language != "" {
+		builder = builder.Where(sq.Eq{"r.primary_language": language})
+	}
+
+	// @> lets postgres use idx_repositories_languages_gin
+	if uses != "" {
+		builder = builder.Where("r.languages @> ARRAY[?]::TEXT[]", uses)
+	}
+
+	return builder
+}
+
+func (r *TrendRepository) queryTrends(ctx context.Context, builder sq.SelectBuilder, limit int, offset int) ([]Trend, error) {
+	sql, args, err := builder.
+		OrderBy("t.stars_diff DESC", "t.repository_id").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	trends := []Trend{}
+	for rows.Next() {
+		var trend Trend
+		err := rows.Scan(
+			&trend.Id,
+			&trend.NameWithOwner,
+			&trend.Description,
+			&trend.PrimaryLanguage,
+			&trend.LanguageColor,
+			&trend.StarCount,
+			&trend.StarsDiff,
+			&trend.StartStarCount,
+		)
+		if err != nil {
+			return trends, err
+		}
+		trends = append(trends, trend)
+	}
+
+	if err := rows.Err(); err != nil {
+		return trends, err
+	}
+
+	return trends, nil
+}
+
+// GetLanguages returns the primary languages of the trending repositories of a period
+func (r *TrendRepository) GetLanguages(ctx context.Context, period TrendPeriod) ([]TrendLanguage, error) {
+	if _, err := ParseTrendPeriod(string(period)); err != nil {
+		return nil, err
+	}
+
+	sql, args, err := sq.
+		Select("r.primary_language", "COALESCE(l.hexcolor, '')", "COUNT(*)").
+		From(pgx.Identifier{period.View()}.Sanitize()+" t").
+		Join("repositories r ON r.id = t.repository_id").
+		LeftJoin("languages l ON l.id = r.primary_language").
+		Where(sq.NotEq{"r.primary_language": nil}).
+		GroupBy("r.primary_language", "l.hexcolor").
+		OrderBy("COUNT(*) DESC", "r.primary_language").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	languages := []TrendLanguage{}
+	for rows.Next() {
+		var language TrendLanguage
+		err := rows.Scan(&language.Name, &language.Hexcolor, &language.Count)
+		if err != nil {
+			return languages, err
+		}
+		languages = append(languages, language)
+	}
+
+	if err := rows.Err(); err != nil {
+		return languages, err
+	}
+
+	return languages, nil
+}
+
+// GetStarsDiff returns the stars a repository gained in the period, 0 if it isn't trending
+func (r *TrendRepository) GetStarsDiff(ctx context.Context, period TrendPeriod, id int) (int, error) {
+	var starsDiff int
+
+	if _, err := ParseTrendPeriod(string(period)); err != nil {
+		return starsDiff, err
+	}
+
+	sql, args, err := sq.
+		Select("stars_diff").
+		From(pgx.Identifier{period.View()}.Sanitize()).
+		Where(sq.Eq{"repository_id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return starsDiff, fmt.Errorf("building SQL: %w", err)
+	}
+
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&starsDiff)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return starsDiff, nil
+		}
+		return starsDiff, err
+	}
+
+	return starsDiff, nil
+}
+
+// GetRank returns the current position of a repository in the view of a period, 0 if it isn't trending
+func (r *TrendRepository) GetRank(ctx context.Context, period TrendPeriod, id int) (int, error) {
+	var rank int
+
+	if _, err := ParseTrendPeriod(string(period)); err != nil {
+		return rank, err
+	}
+
+	sql, args, err := sq.
+		Select("rank").
+		FromSelect(
+			sq.Select("repository_id").
+				Column("row_number() OVER (ORDER BY stars_diff DESC, repository_id) AS rank").
+				From(pgx.Identifier{period.View()}.Sanitize()),
+			"v",
+		).
+		Where(sq.Eq{"repository_id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return rank, fmt.Errorf("building SQL: %w", err)
+	}
+
+	err = r.db.Pool.QueryRow(ctx, sql, args...).Scan(&rank)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return rank, nil
+		}
+		return rank, err
+	}
+
+	return rank, nil
+}
+
+// SaveRankings persists the current contents of the view of a period as today's ranking
+func (r *TrendRepository) SaveRankings(ctx context.Context, period TrendPeriod) error {
+	if _, err := ParseTrendPeriod(string(period)); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := sq.
+		Delete("trend_rankings").
+		Where(sq.Eq{"period": string(period)}).
+		Where("date = CURRENT_DATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to delete rankings: %w", err)
+	}
+
+	sql, args, err = sq.
+		Insert("trend_rankings").
+		Columns("period", "date", "rank", "repository_id", "stars_diff").
+		Select(
+			sq.Select().
+				Column("?::TEXT", string(period)).
+				Column("CURRENT_DATE").
+				Column("row_number() OVER (ORDER BY stars_diff DESC, repository_id)").
+				Column("repository_id").
+				Column("stars_diff").
+				From(pgx.Identifier{period.View()}.Sanitize()),
+		).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to insert rankings: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TrendRepository) GetRankHistory(ctx context.Context, id int, period TrendPeriod, from time.Time, to time.Time) ([]Ranking, error) {
+	if _, err := ParseTrendPeriod(string(period)); err != nil {
+		return nil, err
+	}
+
+	sql, args, err := sq.
+		Select("date", "rank", "stars_diff").
+		From("trend_rankings").
+		Where(sq.Eq{"repository_id": id}).
+		Where(sq.Eq{"period": string(period)}).
+		Where(sq.GtOrEq{"date": from}).
+		Where(sq.LtOrEq{"date": to}).
+		OrderBy("date").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	rankings := []Ranking{}
+	for rows.Next() {
+		var ranking Ranking
+		err := rows.Scan(&ranking.Date, &ranking.Rank, &ranking.StarsDiff)
+		if err != nil {
+			return rankings, err
+		}
+		rankings = append(rankings, ranking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return rankings, err
+	}
+
+	return rankings, nil
+}
+
+// GetClimbers compares the rankings of two dates, repositories that weren't ranked
+// on from count as one rank below the last one of that date
+func (r *TrendRepository) GetClimbers(ctx context.Context, period TrendPeriod, from time.Time, to time.Time, limit int) ([]Climber, error) {
+	sql, args, err := sq.
+		Select("r.id", "r.name_with_owner", "COALESCE(f.rank, 0)", "t.rank").
+		Column(sq.Expr(
+			"COALESCE(f.rank, (SELECT COUNT(*) FROM trend_rankings WHERE period = ? AND date = ?) + 1) - t.rank AS climbed",
+			string(period),
+			from,
+		)).
+		From("trend_rankings t").
+		Join("repositories r ON r.id = t.repository_id").
+		LeftJoin("trend_rankings f ON f.repository_id = t.repository_id AND f.period = t.period AND f.date = ?", from).
+		Where(sq.Eq{"t.period": string(period)}).
+		Where(sq.Eq{"t.date": to}).
+		OrderBy("climbed DESC", "t.rank").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	climbers := []Climber{}
+	for rows.Next() {
+		var climber Climber
+		err := rows.Scan(&climber.Id, &climber.NameWithOwner, &climber.FromRank, &climber.ToRank, &climber.Climbed)
+		if err != nil {
+			return climbers, err
+		}
+		climbers = append(climbers, climber)
+	}
+
+	if err := rows.Err(); err != nil {
+		return climbers, err
+	}
+
+	return climbers, nil
+}
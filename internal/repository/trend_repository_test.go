@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestTrendRepository(t *testing.T) {
+	connString, cleanup, restore, err := testutil.SetupPostgresContainer()
+	if err != nil {
+		t.Fatalf("failed to set up test container: %v", err)
+	}
+	defer cleanup()
+
+	t.Run("Test getting daily trends joins repositories", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &database.Database{Pool: pool}
+		hRepo := NewHistoryRepository(db)
+		tRepo := NewTrendRepository(db)
+
+		today := time.Now().Truncate(24 * time.Hour)
+		err = hRepo.BatchUpsert(ctx, []StarHistoryInput{
+			{Id: 5, Date: today.Add(-24 * time.Hour), StarCount: 950},
+			{Id: 5, Date: today, StarCount: 1000},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = hRepo.RefreshView(ctx, PeriodDaily.View())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trends, err := tRepo.GetTrends(ctx, TrendQuery{Period: PeriodDaily, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(trends) != 1 {
+			t.Fatalf("Expected %d trends to equal 1", len(trends))
+		}
+		if trends[0].NameWithOwner != "glup3/repo0005" {
+			t.Fatalf("Expected %s to equal glup3/repo0005", trends[0].NameWithOwner)
+		}
+		if trends[0].StarsDiff != 50 {
+			t.Fatalf("Expected %d to equal 50", trends[0].StarsDiff)
+		}
+		if trends[0].StartStarCount != 950 {
+			t.Fatalf("Expected %d to equal 950", trends[0].StartStarCount)
+		}
+	})
+
+	t.Run("Test filtering trends by language", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &database.Database{Pool: pool}
+		hRepo := NewHistoryRepository(db)
+		rRepo := NewRepoRepository(db)
+		tRepo := NewTrendRepository(db)
+
+		err = rRepo.UpsertMany(ctx, []RepoInput{
+			{GithubId: "R_kg0005", Name: "glup3", NameWithOwner: "glup3/repo0005", StarCount: 1000, Languages: []string{"Rust", "Shell"}, PrimaryLanguage: "Rust"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = rRepo.UpsertLanguages(ctx, []LanguageInput{{Id: "Rust", Hexcolor: "#dea584"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		today := time.Now().Truncate(24 * time.Hour)
+		for _, id := range []int{4, 5} {
+			err = hRepo.BatchUpsert(ctx, []StarHistoryInput{
+				{Id: id, Date: today.Add(-24 * time.Hour), StarCount: 100},
+				{Id: id, Date: today, StarCount: 200},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = hRepo.RefreshView(ctx, PeriodDaily.View())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trends, err := tRepo.GetTrends(ctx, TrendQuery{Period: PeriodDaily, Language: "Rust", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trends) != 1 || trends[0].Id != 5 {
+			t.Fatalf("Expected only repo 5, got %v", trends)
+		}
+		if trends[0].LanguageColor != "#dea584" {
+			t.Fatalf("Expected %s to equal #dea584", trends[0].LanguageColor)
+		}
+
+		trends, err = tRepo.GetTrends(ctx, TrendQuery{Period: PeriodDaily, Uses: "Shell", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trends) != 1 || trends[0].Id != 5 {
+			t.Fatalf("Expected only repo 5, got %v", trends)
+		}
+	})
+
+	t.Run("Test getting trends as of a past date", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &database.Database{Pool: pool}
+		hRepo := NewHistoryRepository(db)
+		tRepo := NewTrendRepository(db)
+
+		date := func(day int) time.Time {
+			return time.Date(2024, 7, day, 0, 0, 0, 0, time.UTC)
+		}
+
+		err = hRepo.BatchUpsert(ctx, []StarHistoryInput{
+			{Id: 5, Date: date(1), StarCount: 100},
+			{Id: 5, Date: date(7), StarCount: 200},
+			{Id: 5, Date: date(20), StarCount: 900},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trends, err := tRepo.GetTrendsAsOf(ctx, TrendAsOfQuery{Date: date(8), Window: PeriodWeekly.Window(), Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(trends) != 1 {
+			t.Fatalf("Expected %d trends to equal 1", len(trends))
+		}
+		if trends[0].StarsDiff != 100 {
+			t.Fatalf("Expected %d to equal 100", trends[0].StarsDiff)
+		}
+		if trends[0].StarCount != 200 {
+			t.Fatalf("Expected %d to equal 200", trends[0].StarCount)
+		}
+	})
+
+	t.Run("Test saving rankings of a refreshed view", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		db := &database.Database{Pool: pool}
+		hRepo := NewHistoryRepository(db)
+		tRepo := NewTrendRepository(db)
+
+		today := time.Now().Truncate(24 * time.Hour)
+		for id, diff := range map[int]int{4: 100, 5: 50} {
+			err = hRepo.BatchUpsert(ctx, []StarHistoryInput{
+				{Id: id, Date: today.Add(-24 * time.Hour), StarCount: 1000},
+				{Id: id, Date: today, StarCount: 1000 + diff},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = hRepo.RefreshView(ctx, PeriodDaily.View())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// saving twice on the same day replaces the ranking
+		for i := 0; i < 2; i++ {
+			err = tRepo.SaveRankings(ctx, PeriodDaily)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		rankings, err := tRepo.GetRankHistory(ctx, 5, PeriodDaily, today.AddDate(0, 0, -7), today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rankings) != 1 || rankings[0].Rank != 2 || rankings[0].StarsDiff != 50 {
+			t.Fatalf("Expected a single rank 2 with 50 stars, got %v", rankings)
+		}
+
+		_, err = tRepo.GetRankHistory(ctx, 5, TrendPeriod("yearly"), today.AddDate(0, 0, -7), today)
+		if err == nil {
+			t.Fatal("Expected an unknown period to fail")
+		}
+
+		rank, err := tRepo.GetRank(ctx, PeriodDaily, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank != 2 {
+			t.Fatalf("Expected %d to equal 2", rank)
+		}
+
+		climbers, err := tRepo.GetClimbers(ctx, PeriodDaily, today.AddDate(0, 0, -1), today, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(climbers) != 2 || climbers[0].Id != 4 || climbers[0].FromRank != 0 || climbers[0].Climbed != 0 {
+			t.Fatalf("Expected unranked repo 4 first, got %v", climbers)
+		}
+	})
+
+	t.Run("Test getting trends rejects unknown period", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		tRepo := NewTrendRepository(&database.Database{Pool: pool})
+
+		_, err = tRepo.GetTrends(ctx, TrendQuery{Period: "yearly", Limit: 10})
+		if err == nil {
+			t.Fatal("Expected error for unknown period")
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/glup3/TrendyGitHub/internal/db"
+)
+
+// DefaultWatchlist is created by the migration and used when no watchlist is given
+const DefaultWatchlist = "default"
+
+type WatchlistRepository struct {
+	db *db.Database
+}
+
+type TrackedRepo struct {
+	AddedAt        time.Time
+	Watchlist      string
+	NameWithOwner  string
+	Id             int
+	StarCount      int
+	HistoryMissing bool
+}
+
+func NewWatchlistRepository(db *db.Database) *WatchlistRepository {
+	return &WatchlistRepository{
+		db: db,
+	}
+}
+
+// Track adds the repository with githubId to a watchlist, the watchlist is created if it doesn't exist yet
+func (r *WatchlistRepository) Track(ctx context.Context, watchlist string, githubId string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sql, args, err := sq.
+		Insert("watchlists").
+		Columns("name").
+		Values(watchlist).
+		Suffix("ON CONFLICT (name) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to create watchlist: %w", err)
+	}
+
+	sql, args, err = sq.
+		Insert("watchlist_repositories").
+		Columns("watchlist_id", "repository_id").
+		Select(
+			sq.Select("w.id", "r.id").
+				From("watchlists w").
+				Join("repositories r ON r.github_id = ?", githubId).
+				Where(sq.Eq{"w.name": watchlist}),
+		).
+		Suffix("ON CONFLICT (watchlist_id, repository_id) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf("failed to add repository to watchlist: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Untrack returns false if the repository wasn't on the watchlist
+func (r *WatchlistRepository) Untrack(ctx context.Context, watchlist string, nameWithOwner string) (bool, error) {
+	sql, args, err := sq.
+		Delete("watchlist_repositories").
+		Where("watchlist_id = (SELECT id FROM watchlists WHERE name = ?)", watchlist).
+		Where("repository_id = (SELECT id FROM repositories WHERE name_with_owner = ?)", nameWithOwner).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	tag, err := r.db.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// List returns the repositories of a watchlist, all watchlists if watchlist is empty
+func (r *WatchlistRepository) List(ctx context.Context, watchlist string) ([]TrackedRepo, error) {
+	query := sq.
+		Select("w.name", "r.id", "r.name_with_owner", "r.star_count", "r.history_missing", "wr.added_at").
+		From("watchlist_repositories wr").
+		Join("watchlists w ON w.id = wr.watchlist_id").
+		Join("repositories r ON r.id = wr.repository_id").
+		OrderBy("w.name", "r.name_with_owner").
+		PlaceholderFormat(sq.Dollar)
+
+	if watchlist != "" {
+		query = query.Where(sq.Eq{"w.name": watchlist})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	repos := []TrackedRepo{}
+	for rows.Next() {
+		var repo TrackedRepo
+		err := rows.Scan(&repo.Watchlist, &repo.Id, &repo.NameWithOwner, &repo.StarCount, &repo.HistoryMissing, &repo.AddedAt)
+		if err != nil {
+			return repos, err
+		}
+		repos = append(repos, repo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return repos, err
+	}
+
+	return repos, nil
+}
+
+// GetTrackedRepos returns every repository on any watchlist once
+func (r *WatchlistRepository) GetTrackedRepos(ctx context.Context) ([]Repo, error) {
+	sql, args, err := sq.
+		Select("r.id", "r.github_id", "r.star_count", "r.name_with_owner").
+		From("repositories r").
+		Where("EXISTS (SELECT 1 FROM watchlist_repositories wr WHERE wr.repository_id = r.id)").
+		OrderBy("r.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("building SQL: %w", err)
+	}
+
+	rows, err := r.db.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying rows: %w", err)
+	}
+	defer rows.Close()
+
+	repos := []Repo{}
+	for rows.Next() {
+		var repo Repo
+		err := rows.Scan(&repo.Id, &repo.GithubId, &repo.StarCount, &repo.NameWithOwner)
+		if err != nil {
+			return repos, err
+		}
+		repos = append(repos, repo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return repos, err
+	}
+
+	return repos, nil
+}
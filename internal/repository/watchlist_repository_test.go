@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/testutil"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestWatchlistRepository(t *testing.T) {
+	connString, cleanup, restore, err := testutil.SetupPostgresContainer()
+	if err != nil {
+		t.Fatalf("failed to set up test container: %v", err)
+	}
+	defer cleanup()
+
+	t.Run("Test tracking a repo creates the watchlist once", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		r := NewWatchlistRepository(&database.Database{Pool: pool})
+
+		for _, githubId := range []string{"R_kg0001", "R_kg0001", "R_kg0002"} {
+			err = r.Track(ctx, "deps", githubId)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = r.Track(ctx, DefaultWatchlist, "R_kg0001")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tracked, err := r.List(ctx, "deps")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tracked) != 2 {
+			t.Fatalf("Expected %d to equal 2", len(tracked))
+		}
+
+		repos, err := r.GetTrackedRepos(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(repos) != 2 {
+			t.Fatalf("Expected %d to equal 2", len(repos))
+		}
+	})
+
+	t.Run("Test untracking a repo", func(t *testing.T) {
+		t.Cleanup(func() {
+			restore()
+		})
+
+		ctx := context.Background()
+		pool, err := pgxpool.New(ctx, connString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+
+		r := NewWatchlistRepository(&database.Database{Pool: pool})
+
+		err = r.Track(ctx, DefaultWatchlist, "R_kg0003")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		removed, err := r.Untrack(ctx, DefaultWatchlist, "glup3/repo0003")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !removed {
+			t.Fatal("Expected repo to be removed")
+		}
+
+		removed, err = r.Untrack(ctx, DefaultWatchlist, "glup3/repo0003")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed {
+			t.Fatal("Expected repo to be removed only once")
+		}
+	})
+}
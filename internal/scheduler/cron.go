@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed crontab expression: minute hour day-of-month month day-of-week
+type Schedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// anyDay is true if either day field is *, cron then requires both to match
+	anyDay bool
+}
+
+type field struct {
+	name string
+	min  int
+	max  int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 6},
+}
+
+// ParseSchedule supports *, lists (1,2), ranges (0-20) and steps (*/5, 0-30/10)
+func ParseSchedule(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("schedule %q must have %d fields", spec, len(fields))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+
+	return Schedule{
+		minutes:     bits[0],
+		hours:       bits[1],
+		daysOfMonth: bits[2],
+		months:      bits[3],
+		daysOfWeek:  bits[4],
+		anyDay:      strings.HasPrefix(parts[2], "*") || strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s < 1 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, item)
+			}
+			step = s
+		}
+
+		start, end := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, item)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if step > 1 {
+				end = f.max
+			}
+		}
+
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+// Matches reports whether the schedule is due in the minute of t
+func (s Schedule) Matches(t time.Time) bool {
+	if s.minutes&(1<<uint(t.Minute())) == 0 ||
+		s.hours&(1<<uint(t.Hour())) == 0 ||
+		s.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dayOfMonth := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if s.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		time     time.Time
+		expected bool
+	}{
+		{name: "every minute", spec: "* * * * *", time: time.Date(2024, 7, 1, 13, 37, 0, 0, time.UTC), expected: true},
+		{name: "inside range", spec: "0-20 * * * *", time: time.Date(2024, 7, 1, 13, 20, 0, 0, time.UTC), expected: true},
+		{name: "outside range", spec: "0-20 * * * *", time: time.Date(2024, 7, 1, 13, 21, 0, 0, time.UTC), expected: false},
+		{name: "step", spec: "*/5 * * * *", time: time.Date(2024, 7, 1, 13, 35, 0, 0, time.UTC), expected: true},
+		{name: "step miss", spec: "*/5 * * * *", time: time.Date(2024, 7, 1, 13, 36, 0, 0, time.UTC), expected: false},
+		{name: "step from start", spec: "10/20 * * * *", time: time.Date(2024, 7, 1, 13, 50, 0, 0, time.UTC), expected: true},
+		{name: "list", spec: "0 0-14,17-23 * * *", time: time.Date(2024, 7, 1, 15, 0, 0, 0, time.UTC), expected: false},
+		{name: "day of week", spec: "0 0 * * 1", time: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), expected: true},
+		{name: "day of month or week", spec: "0 0 15 * 1", time: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), expected: true},
+		{name: "day of month and any week", spec: "0 0 15 * *", time: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if schedule.Matches(tt.time) != tt.expected {
+				t.Fatalf("Expected %s at %s to be %v", tt.spec, tt.time, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	specs := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"20-10 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseSchedule(spec)
+			if err == nil {
+				t.Fatalf("Expected %q to be rejected", spec)
+			}
+		})
+	}
+}
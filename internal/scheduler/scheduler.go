@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type StepFunc func(ctx context.Context) error
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Steps run in order, a failing step skips the remaining ones
+	Steps []string
+	// After names a job this job waits for if both are running
+	After string
+}
+
+// Scheduler runs jobs every minute their schedule matches, a job never overlaps with itself
+type Scheduler struct {
+	steps   map[string]StepFunc
+	running map[string]chan struct{}
+	now     func() time.Time
+	jobs    []Job
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+func New(jobs []Job, steps map[string]StepFunc) (*Scheduler, error) {
+	names := make(map[string]bool)
+	for _, job := range jobs {
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate job %s", job.Name)
+		}
+		names[job.Name] = true
+
+		for _, step := range job.Steps {
+			if _, ok := steps[step]; !ok {
+				return nil, fmt.Errorf("job %s has unknown step %s", job.Name, step)
+			}
+		}
+	}
+
+	for _, job := range jobs {
+		if job.After != "" && !names[job.After] {
+			return nil, fmt.Errorf("job %s runs after unknown job %s", job.Name, job.After)
+		}
+	}
+
+	return &Scheduler{
+		steps:   steps,
+		running: make(map[string]chan struct{}),
+		now:     time.Now,
+		jobs:    jobs,
+	}, nil
+}
+
+// ParseJobs reads one job per line: <name> <minute> <hour> <day> <month> <weekday> <step>... [after=<job>]
+func ParseJobs(text string) ([]Job, error) {
+	var jobs []Job
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) < 7 {
+			return nil, fmt.Errorf("line %d: expected a name, 5 schedule fields and at least one step", lineNumber)
+		}
+
+		schedule, err := ParseSchedule(strings.Join(parts[1:6], " "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		job := Job{Name: parts[0], Schedule: schedule}
+		for _, part := range parts[6:] {
+			if after, ok := strings.CutPrefix(part, "after="); ok {
+				job.After = after
+				continue
+			}
+			job.Steps = append(job.Steps, part)
+		}
+
+		if len(job.Steps) == 0 {
+			return nil, fmt.Errorf("line %d: job %s has no steps", lineNumber, job.Name)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, scanner.Err()
+}
+
+// Run blocks until ctx is done and then waits for the running jobs to finish
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Info().Int("jobs", len(s.jobs)).Msg("scheduler started")
+
+	for {
+		now := s.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("scheduler stopping - waiting for running jobs")
+			s.wg.Wait()
+			log.Info().Msg("scheduler stopped")
+			return
+		case <-time.After(next.Sub(now)):
+			s.tick(ctx, next)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context, t time.Time) {
+	for _, job := range s.jobs {
+		if job.Schedule.Matches(t) {
+			s.start(ctx, job)
+		}
+	}
+}
+
+func (s *Scheduler) start(ctx context.Context, job Job) {
+	s.mu.Lock()
+	if _, running := s.running[job.Name]; running {
+		s.mu.Unlock()
+		log.Info().Str("job", job.Name).Msg("skipping job because it is still running")
+		return
+	}
+
+	done := make(chan struct{})
+	s.running[job.Name] = done
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, job.Name)
+			s.mu.Unlock()
+			close(done)
+		}()
+
+		if !s.waitFor(ctx, job) {
+			return
+		}
+
+		s.runSteps(ctx, job)
+	}()
+}
+
+// waitFor blocks while the job this job runs after is running
+func (s *Scheduler) waitFor(ctx context.Context, job Job) bool {
+	if job.After == "" {
+		return true
+	}
+
+	s.mu.Lock()
+	dependency, running := s.running[job.After]
+	s.mu.Unlock()
+
+	if !running {
+		return true
+	}
+
+	log.Info().Str("job", job.Name).Str("after", job.After).Msg("waiting for job to finish")
+
+	select {
+	case <-dependency:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Scheduler) runSteps(ctx context.Context, job Job) {
+	start := time.Now()
+	log.Info().Str("job", job.Name).Strs("steps", job.Steps).Msg("job started")
+
+	for _, step := range job.Steps {
+		if ctx.Err() != nil {
+			log.Warn().Str("job", job.Name).Str("step", step).Msg("skipping step because the scheduler is stopping")
+			return
+		}
+
+		err := s.steps[step](ctx)
+		if err != nil && ctx.Err() != nil {
+			log.Warn().Err(err).Str("job", job.Name).Str("step", step).Msg("step interrupted by shutdown")
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Str("job", job.Name).Str("step", step).Msg("step failed - skipping remaining steps")
+			return
+		}
+	}
+
+	log.Info().Str("job", job.Name).Msgf("job finished after %s", time.Since(start))
+}
@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseJobs(t *testing.T) {
+	jobs, err := ParseJobs(`
+# comment
+search   0-20 * * * *  search snapshot
+refresh  0    * * * *  refresh after=search
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("Expected %d jobs to equal 2", len(jobs))
+	}
+	if !reflect.DeepEqual(jobs[0].Steps, []string{"search", "snapshot"}) {
+		t.Fatalf("Expected steps search and snapshot, got %v", jobs[0].Steps)
+	}
+	if jobs[1].After != "search" {
+		t.Fatalf("Expected %s to equal search", jobs[1].After)
+	}
+
+	_, err = ParseJobs("search 0-20 * * * after=refresh")
+	if err == nil {
+		t.Fatal("Expected error for job without steps")
+	}
+}
+
+func TestNewValidatesJobs(t *testing.T) {
+	steps := map[string]StepFunc{"search": func(ctx context.Context) error { return nil }}
+
+	tests := []struct {
+		name string
+		jobs []Job
+	}{
+		{name: "unknown step", jobs: []Job{{Name: "a", Steps: []string{"history"}}}},
+		{name: "duplicate job", jobs: []Job{{Name: "a", Steps: []string{"search"}}, {Name: "a", Steps: []string{"search"}}}},
+		{name: "unknown dependency", jobs: []Job{{Name: "a", Steps: []string{"search"}, After: "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.jobs, steps)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+		})
+	}
+}
+
+func TestSchedulerOrdersAndSkips(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	steps := map[string]StepFunc{
+		"search": func(ctx context.Context) error {
+			<-release
+			record("search")
+			return nil
+		},
+		"snapshot": func(ctx context.Context) error {
+			record("snapshot")
+			return nil
+		},
+		"refresh": func(ctx context.Context) error {
+			record("refresh")
+			return nil
+		},
+	}
+
+	jobs := []Job{
+		{Name: "search", Steps: []string{"search", "snapshot"}},
+		{Name: "refresh", Steps: []string{"refresh"}, After: "search"},
+	}
+
+	s, err := New(jobs, steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	s.start(ctx, jobs[0])
+	s.start(ctx, jobs[1])
+	// still running, must not start a second search
+	s.start(ctx, jobs[0])
+
+	close(release)
+	s.wg.Wait()
+
+	expected := []string{"search", "snapshot", "refresh"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Fatalf("Expected %v to equal %v", order, expected)
+	}
+}
+
+func TestSchedulerStopsAfterFailedStep(t *testing.T) {
+	called := false
+	steps := map[string]StepFunc{
+		"search": func(ctx context.Context) error {
+			return context.DeadlineExceeded
+		},
+		"snapshot": func(ctx context.Context) error {
+			called = true
+			return nil
+		},
+	}
+
+	job := Job{Name: "search", Steps: []string{"search", "snapshot"}}
+	s, err := New([]Job{job}, steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.start(context.Background(), job)
+	s.wg.Wait()
+
+	if called {
+		t.Fatal("Expected snapshot to be skipped after failed search")
+	}
+}
+
+func TestSchedulerRunWaitsForRunningJobs(t *testing.T) {
+	started := make(chan struct{})
+	finished := false
+
+	steps := map[string]StepFunc{
+		"search": func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			finished = true
+			return nil
+		},
+	}
+
+	job := Job{Name: "search", Steps: []string{"search"}}
+	s, err := New([]Job{job}, steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.start(ctx, job)
+	<-started
+	cancel()
+	s.Run(ctx)
+
+	if !finished {
+		t.Fatal("Expected Run to wait for the running job")
+	}
+}
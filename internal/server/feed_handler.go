@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/feed"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const feedEntryLimit = 25
+
+// handleFeed serves /feeds/{period}.{atom,rss} and /feeds/{period}/{language}.{atom,rss}
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	format := path.Ext(r.PathValue("file"))
+	name := strings.TrimSuffix(r.PathValue("file"), format)
+
+	if format != ".atom" && format != ".rss" {
+		http.NotFound(w, r)
+		return
+	}
+
+	periodName, language := name, ""
+	if r.PathValue("period") != "" {
+		periodName, language = r.PathValue("period"), name
+	}
+
+	period, err := repository.ParseTrendPeriod(periodName)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if language != "" {
+		language, err = s.repoRepository.FindLanguage(r.Context(), language)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				http.NotFound(w, r)
+				return
+			}
+			log.Error().Err(err).Msg("failed loading language")
+			http.Error(w, "failed loading language", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	trends, err := s.trends.GetTrends(r.Context(), repository.TrendQuery{
+		Period:   period,
+		Language: language,
+		Limit:    feedEntryLimit,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading trends")
+		http.Error(w, "failed loading trends", http.StatusInternalServerError)
+		return
+	}
+
+	f := buildFeed(period, language, trends, periodStart(period, time.Now()), baseURL(r))
+	f.SelfLink = baseURL(r) + r.URL.Path
+
+	var out []byte
+	if format == ".atom" {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		out, err = f.Atom()
+	} else {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		out, err = f.RSS()
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed rendering feed")
+		http.Error(w, "failed rendering feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(out)
+}
+
+func buildFeed(period repository.TrendPeriod, language string, trends []repository.Trend, date time.Time, base string) feed.Feed {
+	title := fmt.Sprintf("Trending repositories (%s)", period)
+	if language != "" {
+		title = fmt.Sprintf("Trending %s repositories (%s)", language, period)
+	}
+
+	f := feed.Feed{
+		ID:      fmt.Sprintf("urn:trendygithub:feed:%s:%s", period, strings.ToLower(language)),
+		Title:   title,
+		Link:    base + homeURL(period, language),
+		Updated: date,
+	}
+
+	for _, trend := range trends {
+		f.Entries = append(f.Entries, feed.Entry{
+			// the same repository is only announced once per period
+			ID:      fmt.Sprintf("urn:trendygithub:trend:%s:%s:%d", period, date.Format(dateLayout), trend.Id),
+			Title:   fmt.Sprintf("%s (+%s stars)", trend.NameWithOwner, formatNumber(trend.StarsDiff)),
+			Link:    "https://github.com/" + trend.NameWithOwner,
+			Summary: trend.Description,
+			Updated: date,
+		})
+	}
+
+	return f
+}
+
+// periodStart truncates now to the start of the day, ISO week or month of the period
+func periodStart(period repository.TrendPeriod, now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case repository.PeriodWeekly:
+		weekday := (int(start.Weekday()) + 6) % 7 // monday == 0
+		return start.AddDate(0, 0, -weekday)
+	case repository.PeriodMonthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return start
+	}
+}
+
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
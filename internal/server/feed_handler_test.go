@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// 2024-07-04 is a thursday
+	now := time.Date(2024, 7, 4, 15, 30, 0, 0, time.UTC)
+
+	tests := map[repository.TrendPeriod]time.Time{
+		repository.PeriodDaily:   time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC),
+		repository.PeriodWeekly:  time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		repository.PeriodMonthly: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for period, expected := range tests {
+		if got := periodStart(period, now); !got.Equal(expected) {
+			t.Errorf("periodStart(%s) = %s, want %s", period, got, expected)
+		}
+	}
+
+	sunday := time.Date(2024, 7, 7, 23, 0, 0, 0, time.UTC)
+	if got := periodStart(repository.PeriodWeekly, sunday); !got.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("periodStart(weekly) on sunday = %s, want 2024-07-01", got)
+	}
+}
+
+func TestBuildFeedEntriesAreStable(t *testing.T) {
+	date := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	trends := []repository.Trend{{Id: 42, NameWithOwner: "glup3/TrendyGitHub", StarsDiff: 10}}
+
+	first := buildFeed(repository.PeriodWeekly, "Go", trends, date, "http://localhost")
+	trends[0].StarsDiff = 20
+	second := buildFeed(repository.PeriodWeekly, "Go", trends, date, "http://localhost")
+
+	if first.Entries[0].ID != second.Entries[0].ID {
+		t.Fatalf("entry id changed from %s to %s", first.Entries[0].ID, second.Entries[0].ID)
+	}
+	if first.Entries[0].ID != "urn:trendygithub:trend:weekly:2024-07-01:42" {
+		t.Fatalf("unexpected entry id %s", first.Entries[0].ID)
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	homeTrendLimit    = 50
+	homeLanguageLimit = 15
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var hexcolorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{3,8}$`)
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatNumber": formatNumber,
+	"safeCSS":      safeColor,
+}).ParseFS(templateFS, "templates/*.html"))
+
+type homePage struct {
+	Period    string
+	Language  string
+	Periods   []homeTab
+	Languages []homeTab
+	Trends    []repository.Trend
+}
+
+type homeTab struct {
+	Name     string
+	Hexcolor string
+	URL      string
+	Active   bool
+}
+
+func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
+	period, err := repository.ParseTrendPeriod(defaultString(r.URL.Query().Get("period"), string(repository.PeriodDaily)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	language := r.URL.Query().Get("language")
+
+	trends, err := s.trends.GetTrends(r.Context(), repository.TrendQuery{
+		Period:   period,
+		Language: language,
+		Limit:    homeTrendLimit,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading trends")
+		http.Error(w, "failed loading trends", http.StatusInternalServerError)
+		return
+	}
+
+	languages, err := s.trends.GetLanguages(r.Context(), period)
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading trend languages")
+		http.Error(w, "failed loading languages", http.StatusInternalServerError)
+		return
+	}
+
+	page := homePage{
+		Period:   string(period),
+		Language: language,
+		Trends:   trends,
+	}
+
+	for _, p := range repository.TrendPeriods {
+		page.Periods = append(page.Periods, homeTab{
+			Name:   string(p),
+			URL:    homeURL(p, language),
+			Active: p == period,
+		})
+	}
+
+	page.Languages = append(page.Languages, homeTab{
+		Name:   "All",
+		URL:    homeURL(period, ""),
+		Active: language == "",
+	})
+	for i, l := range languages {
+		if i >= homeLanguageLimit && l.Name != language {
+			continue
+		}
+		page.Languages = append(page.Languages, homeTab{
+			Name:     l.Name,
+			Hexcolor: l.Hexcolor,
+			URL:      homeURL(period, l.Name),
+			Active:   l.Name == language,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = templates.ExecuteTemplate(w, "index.html", page)
+	if err != nil {
+		log.Error().Err(err).Msg("failed rendering homepage")
+	}
+}
+
+func homeURL(period repository.TrendPeriod, language string) string {
+	query := url.Values{}
+	query.Set("period", string(period))
+	if language != "" {
+		query.Set("language", language)
+	}
+	return "/?" + query.Encode()
+}
+
+// formatNumber adds thousands separators
+func formatNumber(n int) string {
+	if n < 0 {
+		return "-" + formatNumber(-n)
+	}
+
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// safeColor only lets hex colors from the languages table into style attributes
+func safeColor(color string) template.CSS {
+	if !hexcolorRegex.MatchString(color) {
+		return ""
+	}
+	return template.CSS(color)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glup3/TrendyGitHub/internal/cache"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+)
+
+type fakeTrendLoader struct {
+	queries []repository.TrendQuery
+}
+
+func (l *fakeTrendLoader) GetTrends(ctx context.Context, query repository.TrendQuery) ([]repository.Trend, error) {
+	l.queries = append(l.queries, query)
+	return []repository.Trend{
+		{Id: 1, NameWithOwner: "glup3/TrendyGitHub", Description: "<b>trends</b>", PrimaryLanguage: "Go", LanguageColor: "#00ADD8", StarCount: 1234, StarsDiff: 56},
+	}, nil
+}
+
+func (l *fakeTrendLoader) GetLanguages(ctx context.Context, period repository.TrendPeriod) ([]repository.TrendLanguage, error) {
+	return []repository.TrendLanguage{{Name: "Go", Hexcolor: "#00ADD8", Count: 1}}, nil
+}
+
+func TestHandleHome(t *testing.T) {
+	loader := &fakeTrendLoader{}
+	s := &Server{trends: cache.NewTrendCache(nil, loader), mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleHome)
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?period=weekly&language=Go", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{"glup3/TrendyGitHub", "&lt;b&gt;trends&lt;/b&gt;", "1,234", "+56 stars", "background: #00ADD8", `href="/?language=Go&amp;period=weekly" class="active"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q", want)
+		}
+	}
+
+	if len(loader.queries) != 1 || loader.queries[0].Period != repository.PeriodWeekly || loader.queries[0].Language != "Go" {
+		t.Fatalf("unexpected queries %v", loader.queries)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567", -4200: "-4,200"}
+
+	for input, expected := range tests {
+		if got := formatNumber(input); got != expected {
+			t.Errorf("formatNumber(%d) = %s, want %s", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	apiKeyHeader       = "X-API-Key"
+	rateLimitSweepTime = time.Minute
+)
+
+// rateLimiter is a token bucket per client. Every client starts with burst tokens
+// and gets refilled with refillPerSecond tokens until the bucket is full again.
+type rateLimiter struct {
+	buckets map[string]*tokenBucket
+	// apiKeys are the keys that get a bucket of their own, unknown keys are limited by IP
+	apiKeys         map[string]bool
+	now             func() time.Time
+	lastSweep       time.Time
+	burst           int
+	refillPerSecond float64
+	mu              sync.Mutex
+}
+
+type tokenBucket struct {
+	updatedAt time.Time
+	tokens    float64
+}
+
+type rateLimitResult struct {
+	ResetAt    time.Time
+	RetryAfter time.Duration
+	Limit      int
+	Remaining  int
+	Allowed    bool
+}
+
+func newRateLimiter(burst int, refillPerMinute int, apiKeys []string) *rateLimiter {
+	keys := make(map[string]bool, len(apiKeys))
+	for _, key := range apiKeys {
+		keys[key] = true
+	}
+
+	return &rateLimiter{
+		buckets:         make(map[string]*tokenBucket),
+		apiKeys:         keys,
+		now:             time.Now,
+		burst:           burst,
+		refillPerSecond: float64(refillPerMinute) / 60,
+	}
+}
+
+func (l *rateLimiter) allow(key string) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.burst), updatedAt: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = l.refill(bucket, now)
+	bucket.updatedAt = now
+
+	result := rateLimitResult{Limit: l.burst}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationFor(1 - bucket.tokens)
+	}
+
+	result.Remaining = int(math.Floor(bucket.tokens))
+	result.ResetAt = now.Add(l.durationFor(float64(l.burst) - bucket.tokens))
+
+	return result
+}
+
+func (l *rateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	return math.Min(float64(l.burst), bucket.tokens+elapsed*l.refillPerSecond)
+}
+
+// durationFor returns how long it takes to refill the given amount of tokens
+func (l *rateLimiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.refillPerSecond <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(math.Ceil(tokens / l.refillPerSecond * float64(time.Second)))
+}
+
+// sweep forgets clients whose bucket is full again, they are indistinguishable from new clients
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepTime {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if l.refill(bucket, now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := l.allow(l.clientKey(r))
+
+		// same headers GitHub sends us
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies a client by its API key and falls back to the remote IP.
+// A random key per request must not get a fresh bucket, so only configured keys count.
+// The IP is the one of RemoteAddr, X-Forwarded-For isn't trusted: behind a reverse proxy
+// or the docker network all clients without a key share the bucket of the proxy.
+func (l *rateLimiter) clientKey(r *http.Request) string {
+	if apiKey := r.Header.Get(apiKeyHeader); l.apiKeys[apiKey] {
+		return "key:" + apiKey
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
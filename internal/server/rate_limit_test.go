@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	newLimiter := func(now *time.Time) *rateLimiter {
+		limiter := newRateLimiter(2, 60, []string{"secret"})
+		limiter.now = func() time.Time { return *now }
+		return limiter
+	}
+
+	t.Run("Burst is allowed and then limited", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		limiter := newLimiter(&now)
+
+		for i := 0; i < 2; i++ {
+			if result := limiter.allow("a"); !result.Allowed {
+				t.Fatalf("request %d should be allowed", i)
+			}
+		}
+
+		result := limiter.allow("a")
+		if result.Allowed {
+			t.Fatal("third request should be limited")
+		}
+		if result.RetryAfter != time.Second {
+			t.Fatalf("got retry after %s, want 1s", result.RetryAfter)
+		}
+	})
+
+	t.Run("Tokens are refilled over time", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		limiter := newLimiter(&now)
+
+		limiter.allow("a")
+		limiter.allow("a")
+
+		now = now.Add(time.Second)
+
+		result := limiter.allow("a")
+		if !result.Allowed {
+			t.Fatal("request should be allowed after refill")
+		}
+		if result.Remaining != 0 {
+			t.Fatalf("got remaining %d, want 0", result.Remaining)
+		}
+	})
+
+	t.Run("Clients are limited separately", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		limiter := newLimiter(&now)
+
+		limiter.allow("a")
+		limiter.allow("a")
+
+		if result := limiter.allow("b"); !result.Allowed {
+			t.Fatal("other client should be allowed")
+		}
+	})
+
+	t.Run("Only configured API keys get a bucket of their own", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		limiter := newLimiter(&now)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/trends/daily", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+
+		req.Header.Set(apiKeyHeader, "secret")
+		if got := limiter.clientKey(req); got != "key:secret" {
+			t.Fatalf("got client key %s, want key:secret", got)
+		}
+
+		req.Header.Set(apiKeyHeader, "random")
+		if got := limiter.clientKey(req); got != "ip:192.0.2.1" {
+			t.Fatalf("got client key %s, want ip:192.0.2.1", got)
+		}
+	})
+
+	t.Run("Middleware responds with 429 and Retry-After", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		limiter := newLimiter(&now)
+		handler := limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/api/trends/daily", nil)
+			req.Header.Set(apiKeyHeader, "secret")
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+		}
+
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("got status %d, want 429", rec.Code)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "1" {
+			t.Fatalf("got Retry-After %s, want 1", got)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+			t.Fatalf("got X-RateLimit-Remaining %s, want 0", got)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/chart"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultHistoryDays = 90
+	chartWidth         = 800
+	chartHeight        = 400
+)
+
+type starHistoryPointResponse struct {
+	Date      string `json:"date"`
+	StarCount int    `json:"star_count"`
+}
+
+type starCountResponse struct {
+	NameWithOwner string `json:"name_with_owner"`
+	Date          string `json:"date"`
+	Id            int    `json:"id"`
+	StarCount     int    `json:"star_count"`
+}
+
+type rankingResponse struct {
+	Date      string `json:"date"`
+	Rank      int    `json:"rank"`
+	StarsDiff int    `json:"stars_diff"`
+}
+
+type rankHistoryResponse struct {
+	NameWithOwner string            `json:"name_with_owner"`
+	Period        string            `json:"period"`
+	From          string            `json:"from"`
+	To            string            `json:"to"`
+	Ranks         []rankingResponse `json:"ranks"`
+	Id            int               `json:"id"`
+}
+
+type starHistoryResponse struct {
+	NameWithOwner string                     `json:"name_with_owner"`
+	From          string                     `json:"from"`
+	To            string                     `json:"to"`
+	Bucket        string                     `json:"bucket"`
+	History       []starHistoryPointResponse `json:"history"`
+	Id            int                        `json:"id"`
+}
+
+func (s *Server) handleStarHistory(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.findRepo(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, bucket, ok := parseHistoryRange(w, r)
+	if !ok {
+		return
+	}
+
+	points, err := s.historyRepository.GetStarHistory(r.Context(), repo.Id, from, to, bucket)
+	if err != nil {
+		log.Error().Err(err).Int("id", repo.Id).Str("repository", repo.NameWithOwner).Msg("failed loading star history")
+		writeError(w, http.StatusInternalServerError, "failed loading star history")
+		return
+	}
+
+	history := make([]starHistoryPointResponse, len(points))
+	for i, point := range points {
+		history[i] = starHistoryPointResponse{
+			Date:      point.Date.Format(dateLayout),
+			StarCount: point.StarCount,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, starHistoryResponse{
+		Id:            repo.Id,
+		NameWithOwner: repo.NameWithOwner,
+		From:          from.Format(dateLayout),
+		To:            to.Format(dateLayout),
+		Bucket:        string(bucket),
+		History:       history,
+	})
+}
+
+// handleStarCount returns the star count at or before a date, defaults to today
+func (s *Server) handleStarCount(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.findRepo(w, r)
+	if !ok {
+		return
+	}
+
+	date, err := parseDateParam(r, "date", time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	starCount, err := s.repoRepository.GetStarCount(r.Context(), repo.Id, date)
+	if err != nil {
+		log.Error().Err(err).Int("id", repo.Id).Str("repository", repo.NameWithOwner).Msg("failed loading star count")
+		writeError(w, http.StatusInternalServerError, "failed loading star count")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, starCountResponse{
+		Id:            repo.Id,
+		NameWithOwner: repo.NameWithOwner,
+		Date:          date.Format(dateLayout),
+		StarCount:     starCount,
+	})
+}
+
+func (s *Server) handleRankHistory(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.findRepo(w, r)
+	if !ok {
+		return
+	}
+
+	period, err := repository.ParseTrendPeriod(defaultString(r.URL.Query().Get("period"), string(repository.PeriodDaily)))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "period must be one of daily, weekly or monthly")
+		return
+	}
+
+	from, to, _, ok := parseHistoryRange(w, r)
+	if !ok {
+		return
+	}
+
+	rankings, err := s.trendRepository.GetRankHistory(r.Context(), repo.Id, period, from, to)
+	if err != nil {
+		log.Error().Err(err).Int("id", repo.Id).Str("repository", repo.NameWithOwner).Msg("failed loading rank history")
+		writeError(w, http.StatusInternalServerError, "failed loading rank history")
+		return
+	}
+
+	ranks := make([]rankingResponse, len(rankings))
+	for i, ranking := range rankings {
+		ranks[i] = rankingResponse{
+			Date:      ranking.Date.Format(dateLayout),
+			Rank:      ranking.Rank,
+			StarsDiff: ranking.StarsDiff,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, rankHistoryResponse{
+		Id:            repo.Id,
+		NameWithOwner: repo.NameWithOwner,
+		Period:        string(period),
+		From:          from.Format(dateLayout),
+		To:            to.Format(dateLayout),
+		Ranks:         ranks,
+	})
+}
+
+func (s *Server) handleStarChart(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.findRepo(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, bucket, ok := parseHistoryRange(w, r)
+	if !ok {
+		return
+	}
+
+	points, err := s.historyRepository.GetStarHistory(r.Context(), repo.Id, from, to, bucket)
+	if err != nil {
+		log.Error().Err(err).Int("id", repo.Id).Str("repository", repo.NameWithOwner).Msg("failed loading star history")
+		writeError(w, http.StatusInternalServerError, "failed loading star history")
+		return
+	}
+
+	chartPoints := make([]chart.Point, len(points))
+	for i, point := range points {
+		chartPoints[i] = chart.Point{Time: point.Date, Value: point.StarCount}
+	}
+
+	svg := chart.StarHistory(chartPoints, chart.Options{
+		Title:  repo.NameWithOwner + " stars",
+		Width:  chartWidth,
+		Height: chartHeight,
+	})
+
+	writeSVG(w, svg)
+}
+
+func (s *Server) handleStarBadge(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.findRepo(w, r)
+	if !ok {
+		return
+	}
+
+	starsDiff, err := s.trendRepository.GetStarsDiff(r.Context(), repository.PeriodWeekly, repo.Id)
+	if err != nil {
+		log.Error().Err(err).Int("id", repo.Id).Str("repository", repo.NameWithOwner).Msg("failed loading weekly stars")
+		writeError(w, http.StatusInternalServerError, "failed loading weekly stars")
+		return
+	}
+
+	message := chart.FormatCompact(repo.StarCount)
+	color := "#9f9f9f"
+	if starsDiff > 0 {
+		message += " | +" + chart.FormatCompact(starsDiff) + "/week"
+		color = "#1a7f37"
+	}
+
+	writeSVG(w, chart.Badge("stars", message, color))
+}
+
+// parseHistoryRange reads from, to and bucket of the request and writes an error response if that fails
+func parseHistoryRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, repository.HistoryBucket, bool) {
+	bucket, err := repository.ParseHistoryBucket(defaultString(r.URL.Query().Get("bucket"), string(repository.BucketDay)))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bucket must be one of day, week or month")
+		return time.Time{}, time.Time{}, bucket, false
+	}
+
+	to, err := parseDateParam(r, "to", time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be formatted as YYYY-MM-DD")
+		return time.Time{}, time.Time{}, bucket, false
+	}
+
+	from, err := parseDateParam(r, "from", to.AddDate(0, 0, -defaultHistoryDays))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be formatted as YYYY-MM-DD")
+		return time.Time{}, time.Time{}, bucket, false
+	}
+
+	if from.After(to) {
+		writeError(w, http.StatusBadRequest, "from must not be after to")
+		return time.Time{}, time.Time{}, bucket, false
+	}
+
+	return from, to, bucket, true
+}
+
+func writeSVG(w http.ResponseWriter, svg []byte) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(svg)
+}
+
+// findRepo resolves the {owner}/{name} path of the request and writes an error response if that fails
+func (s *Server) findRepo(w http.ResponseWriter, r *http.Request) (repository.Repo, bool) {
+	nameWithOwner := r.PathValue("owner") + "/" + r.PathValue("name")
+
+	repo, err := s.repoRepository.FindByNameWithOwner(r.Context(), nameWithOwner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "repository "+nameWithOwner+" is not tracked")
+			return repo, false
+		}
+
+		log.Error().Err(err).Str("repository", nameWithOwner).Msg("failed loading repository")
+		writeError(w, http.StatusInternalServerError, "failed loading repository")
+		return repo, false
+	}
+
+	return repo, true
+}
+
+func defaultString(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/cache"
+	database "github.com/glup3/TrendyGitHub/internal/db"
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultLimit = 25
+	maxLimit     = 100
+
+	shutdownTimeout = 10 * time.Second
+)
+
+const dateLayout = "2006-01-02"
+
+type Server struct {
+	trends            *cache.TrendCache
+	trendRepository   *repository.TrendRepository
+	repoRepository    *repository.RepoRepository
+	historyRepository *repository.HistoryRepository
+	rateLimiter       *rateLimiter
+	mux               *http.ServeMux
+	// handler is mux behind the rate limiter
+	handler http.Handler
+}
+
+type Options struct {
+	// RateLimitBurst is the amount of requests a client can make at once
+	RateLimitBurst int
+	// RateLimitPerMinute is the amount of requests a client gets back every minute
+	RateLimitPerMinute int
+	// APIKeys get a rate limit of their own instead of the one of their IP
+	APIKeys []string
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewServer(db *database.Database, opts Options) *Server {
+	trendRepository := repository.NewTrendRepository(db)
+
+	s := &Server{
+		trends:            cache.NewTrendCache(db, trendRepository),
+		trendRepository:   trendRepository,
+		repoRepository:    repository.NewRepoRepository(db),
+		historyRepository: repository.NewHistoryRepository(db),
+		rateLimiter:       newRateLimiter(opts.RateLimitBurst, opts.RateLimitPerMinute, opts.APIKeys),
+		mux:               http.NewServeMux(),
+	}
+
+	s.routes()
+	s.handler = s.rateLimiter.middleware(s.mux)
+
+	return s
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /{$}", s.handleHome)
+	s.mux.HandleFunc("GET /api/trends/{period}", s.handleTrends)
+	s.mux.HandleFunc("GET /api/trends/asof", s.handleTrendsAsOf)
+	s.mux.HandleFunc("GET /api/trends/{period}/languages", s.handleTrendLanguages)
+	s.mux.HandleFunc("GET /api/trends/{period}/climbers", s.handleClimbers)
+	s.mux.HandleFunc("GET /api/repos/{owner}/{name}/history", s.handleStarHistory)
+	s.mux.HandleFunc("GET /api/repos/{owner}/{name}/stars", s.handleStarCount)
+	s.mux.HandleFunc("GET /api/repos/{owner}/{name}/ranks", s.handleRankHistory)
+	s.mux.HandleFunc("GET /api/repos/{owner}/{name}/chart.svg", s.handleStarChart)
+	s.mux.HandleFunc("GET /api/repos/{owner}/{name}/badge.svg", s.handleStarBadge)
+	s.mux.HandleFunc("GET /feeds/{file}", s.handleFeed)
+	s.mux.HandleFunc("GET /feeds/{period}/{file}", s.handleFeed)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves until ctx is done and then waits for open requests to finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	go s.trends.Listen(ctx)
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+
+		log.Info().Msg("shutting down trends API")
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		shutdownErr <- httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info().Str("addr", addr).Msg("serving trends API")
+
+	err := httpServer.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-shutdownErr
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Error().Err(err).Msg("failed writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func parseIntParam(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func parseDateParam(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(dateLayout, value)
+}
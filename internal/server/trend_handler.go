@@ -0,0 +1,272 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/glup3/TrendyGitHub/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+type trendResponse struct {
+	NameWithOwner   string `json:"name_with_owner"`
+	Description     string `json:"description"`
+	PrimaryLanguage string `json:"primary_language"`
+	LanguageColor   string `json:"language_color"`
+	Id              int    `json:"id"`
+	StarCount       int    `json:"star_count"`
+	StarsDiff       int    `json:"stars_diff"`
+}
+
+type trendsResponse struct {
+	Period   string          `json:"period"`
+	Language string          `json:"language,omitempty"`
+	Uses     string          `json:"uses,omitempty"`
+	Trends   []trendResponse `json:"trends"`
+	Limit    int             `json:"limit"`
+	Offset   int             `json:"offset"`
+}
+
+type trendsAsOfResponse struct {
+	Date     string          `json:"date"`
+	Window   string          `json:"window"`
+	Language string          `json:"language,omitempty"`
+	Uses     string          `json:"uses,omitempty"`
+	Trends   []trendResponse `json:"trends"`
+	Limit    int             `json:"limit"`
+	Offset   int             `json:"offset"`
+}
+
+type climberResponse struct {
+	NameWithOwner string `json:"name_with_owner"`
+	Id            int    `json:"id"`
+	FromRank      *int   `json:"from_rank"`
+	ToRank        int    `json:"to_rank"`
+	Climbed       int    `json:"climbed"`
+}
+
+type climbersResponse struct {
+	Period   string            `json:"period"`
+	From     string            `json:"from"`
+	To       string            `json:"to"`
+	Climbers []climberResponse `json:"climbers"`
+}
+
+type languageResponse struct {
+	Name     string `json:"name"`
+	Hexcolor string `json:"hexcolor"`
+	Count    int    `json:"count"`
+}
+
+type languagesResponse struct {
+	Period    string             `json:"period"`
+	Languages []languageResponse `json:"languages"`
+}
+
+func (s *Server) handleTrends(w http.ResponseWriter, r *http.Request) {
+	period, err := repository.ParseTrendPeriod(r.PathValue("period"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	limit, err := parseIntParam(r, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	offset, err := parseIntParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	query := repository.TrendQuery{
+		Period:   period,
+		Language: r.URL.Query().Get("language"),
+		Uses:     r.URL.Query().Get("uses"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+
+	trends, err := s.trends.GetTrends(r.Context(), query)
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading trends")
+		writeError(w, http.StatusInternalServerError, "failed loading trends")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, trendsResponse{
+		Period:   string(period),
+		Language: query.Language,
+		Uses:     query.Uses,
+		Trends:   mapTrends(trends),
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// handleTrendsAsOf computes the trends of a past date for a period or a custom amount of days
+func (s *Server) handleTrendsAsOf(w http.ResponseWriter, r *http.Request) {
+	date, err := parseDateParam(r, "date", time.Time{})
+	if err != nil || date.IsZero() {
+		writeError(w, http.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	window := repository.PeriodDaily.Window()
+	if r.URL.Query().Has("days") {
+		days, err := parseIntParam(r, "days", 0)
+		if err != nil || days < 1 || days > repository.MaxAsOfDays {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", repository.MaxAsOfDays))
+			return
+		}
+		window = fmt.Sprintf("%d day", days)
+	} else if r.URL.Query().Has("period") {
+		period, err := repository.ParseTrendPeriod(r.URL.Query().Get("period"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "period must be one of daily, weekly or monthly")
+			return
+		}
+		window = period.Window()
+	}
+
+	limit, err := parseIntParam(r, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	offset, err := parseIntParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	query := repository.TrendAsOfQuery{
+		Date:     date,
+		Window:   window,
+		Language: r.URL.Query().Get("language"),
+		Uses:     r.URL.Query().Get("uses"),
+		Limit:    limit,
+		Offset:   offset,
+	}
+
+	trends, err := s.trendRepository.GetTrendsAsOf(r.Context(), query)
+	if err != nil {
+		log.Error().Err(err).Time("date", date).Str("window", window).Msg("failed loading trends as of date")
+		writeError(w, http.StatusInternalServerError, "failed loading trends")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, trendsAsOfResponse{
+		Date:     date.Format(dateLayout),
+		Window:   window,
+		Language: query.Language,
+		Uses:     query.Uses,
+		Trends:   mapTrends(trends),
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// handleClimbers compares the persisted rankings of two dates, defaults to yesterday and today
+func (s *Server) handleClimbers(w http.ResponseWriter, r *http.Request) {
+	period, err := repository.ParseTrendPeriod(r.PathValue("period"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	to, err := parseDateParam(r, "to", time.Now().UTC().Truncate(24*time.Hour))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	from, err := parseDateParam(r, "from", to.AddDate(0, 0, -1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	limit, err := parseIntParam(r, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	climbers, err := s.trendRepository.GetClimbers(r.Context(), period, from, to, limit)
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading climbers")
+		writeError(w, http.StatusInternalServerError, "failed loading climbers")
+		return
+	}
+
+	responses := make([]climberResponse, len(climbers))
+	for i, climber := range climbers {
+		responses[i] = climberResponse{
+			Id:            climber.Id,
+			NameWithOwner: climber.NameWithOwner,
+			ToRank:        climber.ToRank,
+			Climbed:       climber.Climbed,
+		}
+		if climber.FromRank > 0 {
+			responses[i].FromRank = &climber.FromRank
+		}
+	}
+
+	writeJSON(w, http.StatusOK, climbersResponse{
+		Period:   string(period),
+		From:     from.Format(dateLayout),
+		To:       to.Format(dateLayout),
+		Climbers: responses,
+	})
+}
+
+func (s *Server) handleTrendLanguages(w http.ResponseWriter, r *http.Request) {
+	period, err := repository.ParseTrendPeriod(r.PathValue("period"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	languages, err := s.trends.GetLanguages(r.Context(), period)
+	if err != nil {
+		log.Error().Err(err).Str("period", string(period)).Msg("failed loading trend languages")
+		writeError(w, http.StatusInternalServerError, "failed loading languages")
+		return
+	}
+
+	responses := make([]languageResponse, len(languages))
+	for i, language := range languages {
+		responses[i] = languageResponse{
+			Name:     language.Name,
+			Hexcolor: language.Hexcolor,
+			Count:    language.Count,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, languagesResponse{
+		Period:    string(period),
+		Languages: responses,
+	})
+}
+
+func mapTrends(trends []repository.Trend) []trendResponse {
+	responses := make([]trendResponse, len(trends))
+	for i, trend := range trends {
+		responses[i] = trendResponse{
+			Id:              trend.Id,
+			NameWithOwner:   trend.NameWithOwner,
+			Description:     trend.Description,
+			PrimaryLanguage: trend.PrimaryLanguage,
+			LanguageColor:   trend.LanguageColor,
+			StarCount:       trend.StarCount,
+			StarsDiff:       trend.StarsDiff,
+		}
+	}
+	return responses
+}
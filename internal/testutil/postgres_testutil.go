@@ -4,14 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/glup3/TrendyGitHub/db/migrations"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -38,13 +37,13 @@ func SetupPostgresContainer() (string, func(), func(), error) {
 		return "", nil, nil, fmt.Errorf("failed to get connection string: %w", err)
 	}
 
-	projectRoot, err := findProjectRoot()
+	source, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		log.Fatalf("failed to find project root: %v", err)
+		container.Terminate(ctx)
+		return "", nil, nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
 
-	migrationsPath := filepath.Join(projectRoot, "db", "migrations")
-	m, err := migrate.New("file://"+migrationsPath, connString)
+	m, err := migrate.NewWithSourceInstance("iofs", source, connString)
 	if err != nil {
 		container.Terminate(ctx)
 		return "", nil, nil, fmt.Errorf("failed to create migrate instance: %w", err)
@@ -123,22 +122,3 @@ func seedDatabase(ctx context.Context, connString string) error {
 
 	return nil
 }
-
-func findProjectRoot() (string, error) {
-	dir, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("failed to get working directory: %w", err)
-	}
-
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir, nil
-		}
-		if dir == filepath.Dir(dir) {
-			break
-		}
-		dir = filepath.Dir(dir)
-	}
-
-	return "", fmt.Errorf("project root not found")
-}
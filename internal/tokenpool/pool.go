@@ -0,0 +1,242 @@
+package tokenpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type API string
+
+const (
+	GraphQL API = "graphql"
+	REST    API = "rest"
+)
+
+// unknownRemaining ranks tokens that weren't used yet before all tokens with a known budget
+const unknownRemaining = math.MaxInt32
+
+// Budget is what GitHub reported about a token in its last response
+type Budget struct {
+	ResetAt   time.Time
+	Limit     int
+	Remaining int
+	Known     bool
+}
+
+type TokenBudget struct {
+	// Name identifies a token without revealing it
+	Name    string
+	GraphQL Budget
+	REST    Budget
+}
+
+// Pool hands out the GitHub token with the most remaining points of an API
+type Pool struct {
+	tokens []*token
+	mu     sync.Mutex
+}
+
+type token struct {
+	budgets map[API]Budget
+	value   string
+}
+
+type tokenKey struct{}
+
+func New(tokens []string) (*Pool, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("token pool needs at least one token")
+	}
+
+	p := &Pool{}
+	for _, value := range tokens {
+		p.tokens = append(p.tokens, &token{value: value, budgets: make(map[API]Budget)})
+	}
+
+	return p, nil
+}
+
+func (p *Pool) Len() int {
+	return len(p.tokens)
+}
+
+// WithToken pins the requests made with ctx to a token instead of letting the pool pick one
+func WithToken(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, value)
+}
+
+// Pick returns the token with the most remaining points, a token counts as full again after its reset
+func (p *Pool) Pick(api API) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	best := p.tokens[0]
+	bestRemaining := -1
+
+	for _, t := range p.tokens {
+		remaining := t.remaining(api, now)
+		if remaining > bestRemaining {
+			best, bestRemaining = t, remaining
+		}
+	}
+
+	return best.value
+}
+
+func (t *token) remaining(api API, now time.Time) int {
+	budget, ok := t.budgets[api]
+	if !ok || !budget.Known || now.After(budget.ResetAt) {
+		return unknownRemaining
+	}
+	return budget.Remaining
+}
+
+func (p *Pool) Update(value string, api API, budget Budget) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range p.tokens {
+		if t.value == value {
+			budget.Known = true
+			t.budgets[api] = budget
+			return
+		}
+	}
+}
+
+func (p *Pool) Budgets() []TokenBudget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	budgets := make([]TokenBudget, len(p.tokens))
+	for i, t := range p.tokens {
+		budgets[i] = TokenBudget{
+			Name:    fmt.Sprintf("#%d %s", i+1, mask(t.value)),
+			GraphQL: t.budgets[GraphQL],
+			REST:    t.budgets[REST],
+		}
+	}
+
+	return budgets
+}
+
+// Total sums up the remaining points of api over all tokens, ResetAt is the earliest reset.
+// It is only Known while every token has a budget that didn't pass its reset yet.
+func (p *Pool) Total(api API) Budget {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	total := Budget{Known: true}
+
+	for _, t := range p.tokens {
+		budget, ok := t.budgets[api]
+		if !ok || !budget.Known || now.After(budget.ResetAt) {
+			return Budget{}
+		}
+
+		total.Remaining += budget.Remaining
+		total.Limit += budget.Limit
+		if total.ResetAt.IsZero() || budget.ResetAt.Before(total.ResetAt) {
+			total.ResetAt = budget.ResetAt
+		}
+	}
+
+	return total
+}
+
+func mask(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return "..." + value[len(value)-4:]
+}
+
+// ForEach calls fn once per token with ctx pinned to it. Tokens whose call fails are skipped,
+// an error is only returned if it failed for every token.
+func (p *Pool) ForEach(ctx context.Context, fn func(ctx context.Context) error) error {
+	var errs []error
+
+	for _, t := range p.tokens {
+		err := fn(WithToken(ctx, t.value))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("token %s: %w", mask(t.value), err))
+		}
+	}
+
+	if len(errs) == len(p.tokens) {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// Transport authenticates every request with a token of the pool and records the
+// budget GitHub reports in the X-RateLimit headers of the response
+func (p *Pool) Transport(api API, acceptHeader string, wrapped http.RoundTripper) http.RoundTripper {
+	return &transport{pool: p, api: api, acceptHeader: acceptHeader, wrapped: wrapped}
+}
+
+type transport struct {
+	pool         *Pool
+	wrapped      http.RoundTripper
+	api          API
+	acceptHeader string
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	value, ok := req.Context().Value(tokenKey{}).(string)
+	if !ok {
+		value = t.pool.Pick(t.api)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "bearer "+value)
+	req.Header.Set("Accept", t.acceptHeader)
+	req.Header.Set("X-Github-Next-Global-ID", "1")
+
+	resp, err := t.wrapped.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if api, budget, ok := parseBudget(resp.Header, t.api); ok {
+		t.pool.Update(value, api, budget)
+	}
+
+	return resp, nil
+}
+
+// parseBudget reads the X-RateLimit headers, the search API has its own budget and is ignored
+func parseBudget(header http.Header, fallback API) (API, Budget, bool) {
+	api := fallback
+	switch header.Get("X-RateLimit-Resource") {
+	case "graphql":
+		api = GraphQL
+	case "core":
+		api = REST
+	case "":
+	default:
+		return api, Budget{}, false
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return api, Budget{}, false
+	}
+
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return api, Budget{}, false
+	}
+
+	return api, Budget{ResetAt: time.Unix(reset, 0), Limit: limit, Remaining: remaining}, true
+}
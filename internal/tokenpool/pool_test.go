@@ -0,0 +1,142 @@
+package tokenpool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPickPrefersMostRemaining(t *testing.T) {
+	pool, err := New([]string{"token-a", "token-b", "token-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetAt := time.Now().Add(time.Hour)
+	pool.Update("token-a", GraphQL, Budget{ResetAt: resetAt, Remaining: 10})
+	pool.Update("token-b", GraphQL, Budget{ResetAt: resetAt, Remaining: 500})
+
+	// token-c has no known budget yet and gets tried first
+	if got := pool.Pick(GraphQL); got != "token-c" {
+		t.Fatalf("Expected %s to equal token-c", got)
+	}
+
+	pool.Update("token-c", GraphQL, Budget{ResetAt: resetAt, Remaining: 0})
+	if got := pool.Pick(GraphQL); got != "token-b" {
+		t.Fatalf("Expected %s to equal token-b", got)
+	}
+
+	// an exhausted token is full again after its reset
+	pool.Update("token-c", GraphQL, Budget{ResetAt: time.Now().Add(-time.Second), Remaining: 0})
+	if got := pool.Pick(GraphQL); got != "token-c" {
+		t.Fatalf("Expected %s to equal token-c", got)
+	}
+
+	// budgets are tracked per API
+	if got := pool.Pick(REST); got != "token-a" {
+		t.Fatalf("Expected %s to equal token-a", got)
+	}
+}
+
+func TestTransportRecordsBudget(t *testing.T) {
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining := "4000"
+		if r.Header.Get("Authorization") == "bearer token-b" {
+			remaining = "3"
+		}
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", remaining)
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	}))
+	defer server.Close()
+
+	pool, err := New([]string{"token-a", "token-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: pool.Transport(REST, "application/json", http.DefaultTransport)}
+
+	err = pool.ForEach(context.Background(), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budgets := pool.Budgets()
+	if budgets[1].REST.Remaining != 3 || !budgets[1].REST.ResetAt.Equal(resetAt) {
+		t.Fatalf("Expected 3 remaining until %s, got %v", resetAt, budgets[1].REST)
+	}
+	if budgets[0].GraphQL.Known {
+		t.Fatalf("Expected no GraphQL budget, got %v", budgets[0].GraphQL)
+	}
+	if got := pool.Pick(REST); got != "token-a" {
+		t.Fatalf("Expected %s to equal token-a", got)
+	}
+}
+
+func TestForEachFailsOnlyIfAllTokensFail(t *testing.T) {
+	pool, err := New([]string{"token-a", "token-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pool.ForEach(context.Background(), func(ctx context.Context) error {
+		if ctx.Value(tokenKey{}) == "token-a" {
+			return errors.New("bad credentials")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected %v to be nil", err)
+	}
+
+	err = pool.ForEach(context.Background(), func(ctx context.Context) error {
+		return errors.New("bad credentials")
+	})
+	if err == nil {
+		t.Fatal("Expected error when every token fails")
+	}
+}
+
+func TestTotalSumsKnownBudgets(t *testing.T) {
+	pool, err := New([]string{"token-a", "token-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	soon := time.Now().Add(time.Minute)
+	pool.Update("token-a", REST, Budget{ResetAt: time.Now().Add(time.Hour), Limit: 5000, Remaining: 10})
+
+	// token-b wasn't used yet
+	if got := pool.Total(REST); got.Known {
+		t.Fatalf("Expected %+v to be unknown", got)
+	}
+
+	pool.Update("token-b", REST, Budget{ResetAt: soon, Limit: 5000, Remaining: 20})
+	got := pool.Total(REST)
+	if !got.Known || got.Remaining != 30 || got.Limit != 10_000 || !got.ResetAt.Equal(soon) {
+		t.Fatalf("Expected %+v to sum up to 30 remaining points", got)
+	}
+
+	// a budget past its reset is unknown again
+	pool.Update("token-b", REST, Budget{ResetAt: time.Now().Add(-time.Second), Remaining: 0})
+	if got := pool.Total(REST); got.Known {
+		t.Fatalf("Expected %+v to be unknown", got)
+	}
+}